@@ -0,0 +1,113 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root
+
+package bench
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+)
+
+// LatestBaseline names the default baseline, which holds the results of the
+// most recent run that did not save into a named baseline.
+const LatestBaseline = "latest"
+
+// baselineName returns the name under which a baseline is stored, where an
+// empty name stands for the latest results.
+func baselineName(baseline string) string {
+	if baseline == "" {
+		return LatestBaseline
+	}
+	return baseline
+}
+
+// get returns the results of a baseline, keyed by benchmark name.
+func (r Results) get(baseline string) map[string]Result {
+	return r[baselineName(baseline)]
+}
+
+// put stores a result in a baseline, creating the baseline when needed.
+func (r Results) put(baseline string, result Result) {
+	baseline = baselineName(baseline)
+	if r[baseline] == nil {
+		r[baseline] = make(map[string]Result)
+	}
+	r[baseline][result.Name] = result
+}
+
+// Baselines returns the sorted names of the baselines stored in the results file
+// configured by the options.
+func Baselines(opts ...Option) ([]string, error) {
+	cfg := configure(opts)
+	results, err := cfg.codec.Load(cfg.filename)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]string, 0, len(results))
+	for baseline, stored := range results {
+		if len(stored) > 0 {
+			out = append(out, baseline)
+		}
+	}
+	slices.Sort(out)
+	return out, nil
+}
+
+// CopyBaseline replaces the baseline "to" with a snapshot of the results stored
+// in the baseline "from" of the results file configured by the options.
+func CopyBaseline(from, to string, opts ...Option) error {
+	return updateResults(configure(opts), func(results Results) error {
+		snapshot := maps.Clone(results.get(from))
+		if len(snapshot) == 0 {
+			return fmt.Errorf("bench: baseline %q not found", from)
+		}
+
+		results[baselineName(to)] = snapshot
+		return nil
+	})
+}
+
+// DeleteBaseline removes a baseline and its results from the results file
+// configured by the options.
+func DeleteBaseline(baseline string, opts ...Option) error {
+	return updateResults(configure(opts), func(results Results) error {
+		if len(results.get(baseline)) == 0 {
+			return fmt.Errorf("bench: baseline %q not found", baseline)
+		}
+
+		delete(results, baselineName(baseline))
+		return nil
+	})
+}
+
+// configure returns the configuration of the options, for the functions which
+// work on results files outside of a run, such as Analyze or Merge.
+func configure(opts []Option) config {
+	cfg := defaultConfig()
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	cfg.normalize()
+	return cfg
+}
+
+// updateResults applies a change to the configured results file under the
+// cross-process lock, saving it only when the change succeeds.
+func updateResults(cfg config, fn func(Results) error) error {
+	unlock, err := lockCodec(cfg.codec, cfg.filename)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	results, err := cfg.codec.Load(cfg.filename)
+	if err != nil {
+		return err
+	}
+	if err := fn(results); err != nil {
+		return err
+	}
+	return cfg.codec.Save(cfg.filename, results)
+}
@@ -0,0 +1,121 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root
+
+package bench
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResultsBaselines(t *testing.T) {
+	results := make(Results)
+	results.put("", Result{Name: "a"})
+	results.put("main", Result{Name: "@x/b"})
+
+	assert.Contains(t, results[LatestBaseline], "a")
+	assert.Equal(t, results.get(LatestBaseline), results.get(""))
+	assert.Equal(t, "@x/b", results.get("main")["@x/b"].Name)
+	assert.Nil(t, results.get("missing"))
+}
+
+func TestRunWithBaselines(t *testing.T) {
+	file := "test_baseline.json"
+	defer removeResults(file)
+
+	run := func(opts ...Option) (report Report) {
+		opts = append(opts, WithFile(file), WithSamples(4), WithDuration(time.Millisecond), WithBootstrap(100))
+		Run(func(b *B) {
+			report = b.Run("bench", func(i int) {})
+		}, opts...)
+		return
+	}
+
+	// Save into a named baseline, leaving the latest results untouched
+	run(WithSaveBaseline("main"))
+	names, err := Baselines(WithFile(file))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"main"}, names)
+
+	// Compare the latest run against the baseline
+	assert.Zero(t, run().Confidence)
+	assert.NotZero(t, run(WithBaseline("main")).Confidence)
+
+	names, err = Baselines(WithFile(file))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"latest", "main"}, names)
+
+	// Results keep their names, with baselines in a separate field
+	loaded, err := jsonCodec{}.Load(file)
+	assert.NoError(t, err)
+	assert.Equal(t, "bench", loaded["main"]["bench"].Name)
+	assert.Equal(t, "bench", loaded[LatestBaseline]["bench"].Name)
+
+	data, err := os.ReadFile(file)
+	assert.NoError(t, err)
+	assert.Contains(t, string(data), `"baselines"`)
+	assert.NotContains(t, string(data), "@main")
+}
+
+func TestRunWithBaselineLikeName(t *testing.T) {
+	file := "test_baseline_name.json"
+	defer removeResults(file)
+
+	Run(func(b *B) {
+		b.Run("@x/bench", func(i int) {})
+	}, WithFile(file), WithSamples(2), WithDuration(time.Millisecond))
+
+	names, err := Baselines(WithFile(file))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"latest"}, names)
+}
+
+func TestCopyAndDeleteBaseline(t *testing.T) {
+	file := "test_baseline_copy.gob"
+	defer removeResults(file)
+
+	assert.NoError(t, gobCodec{}.Save(file, Results{
+		LatestBaseline: {
+			"a": {Name: "a", Samples: []float64{1}},
+			"b": {Name: "b", Samples: []float64{2}},
+		},
+		"old": {"stale": {Name: "stale", Samples: []float64{3}}},
+	}))
+
+	// Copying replaces the target with a snapshot of the source
+	opt := WithFile(file)
+	assert.NoError(t, CopyBaseline(LatestBaseline, "old", opt))
+	loaded, err := gobCodec{}.Load(file)
+	assert.NoError(t, err)
+	assert.Len(t, loaded, 2)
+	assert.Equal(t, []float64{2}, loaded["old"]["b"].Samples)
+	assert.Equal(t, "b", loaded["old"]["b"].Name)
+	assert.NotContains(t, loaded["old"], "stale")
+
+	assert.NoError(t, CopyBaseline("old", "main", opt))
+	assert.Error(t, CopyBaseline("missing", "main", opt))
+
+	assert.NoError(t, DeleteBaseline("old", opt))
+	assert.Error(t, DeleteBaseline("old", opt))
+
+	names, err := Baselines(opt)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"latest", "main"}, names)
+}
+
+func TestBaselinesWithCodec(t *testing.T) {
+	codec := &memoryCodec{files: make(map[string]Results)}
+	codec.files["results"] = Results{"main": {"a": {Name: "a"}}}
+
+	opts := []Option{WithFile("results"), WithCodec(codec)}
+	assert.NoError(t, CopyBaseline("main", "copy", opts...))
+	names, err := Baselines(opts...)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"copy", "main"}, names)
+
+	_, err = os.Stat("results")
+	assert.True(t, os.IsNotExist(err))
+}
@@ -4,8 +4,13 @@
 package bench
 
 import (
+	"errors"
+	"flag"
 	"fmt"
+	"io"
+	"os"
 	"runtime"
+	"slices"
 	"strings"
 	"testing"
 	"time"
@@ -13,26 +18,31 @@ import (
 
 const (
 	// Default sampling configuration
-	minSamples        = 2
-	defaultSamples    = 100
-	defaultDuration   = 10 * time.Millisecond
-	defaultTableFmt   = "%-20s %-12s %-12s %-12s %-18s %-18s\n"
-	defaultFilename   = "bench.gob"
-	defaultConfidence = 99.9
-	defaultThreshold  = 5.0
-	defaultBootstrap  = 100000
+	minSamples            = 2
+	defaultSamples        = 100
+	defaultDuration       = 10 * time.Millisecond
+	defaultTableFmt       = "%-20s %-12s %-12s %-12s %-12s %-18s %-18s\n"
+	prevTableFmt          = "%-20s %-12s %-12s %-12s %-12s %-18s\n"
+	defaultFilename       = "bench.gob"
+	defaultConfidence     = 99.9
+	defaultThreshold      = 5.0
+	defaultAllocThreshold = 5.0
+	defaultBootstrap      = 100000
+	defaultCheckpoint     = 5 * time.Second
 )
 
 func defaultConfig() config {
 	return config{
-		filename:   defaultFilename,
-		samples:    defaultSamples,
-		duration:   defaultDuration,
-		tableFmt:   defaultTableFmt,
-		confidence: defaultConfidence,
-		threshold:  defaultThreshold,
-		bootstrap:  defaultBootstrap,
-		codec:      gobCodec{},
+		filename:       defaultFilename,
+		samples:        defaultSamples,
+		duration:       defaultDuration,
+		tableFmt:       defaultTableFmt,
+		confidence:     defaultConfidence,
+		threshold:      defaultThreshold,
+		allocThreshold: defaultAllocThreshold,
+		bootstrap:      defaultBootstrap,
+		checkpoint:     defaultCheckpoint,
+		codec:          gobCodec{},
 	}
 }
 
@@ -41,89 +51,183 @@ type Result struct {
 	Name      string    `json:"name"`
 	Samples   []float64 `json:"samples"`
 	Allocs    []float64 `json:"allocs"`
+	Bytes     []float64 `json:"bytes"`
+	Outliers  int       `json:"outliers"`
+	Unstable  bool      `json:"unstable"`
+	Tags      []string  `json:"tags,omitempty"`
 	Timestamp int64     `json:"timestamp"`
 }
 
 // B manages benchmarks and handles persistence
 type B struct {
 	config
-	t testing.TB
+	t           testing.TB
+	prevReports []Report          // comparisons against the previous run
+	refReports  []Report          // comparisons against the reference implementations
+	matcher     *matcher          // compiled include and exclude patterns
+	results     Results           // results loaded once per suite, plus the ones saved since
+	pending     map[string]Result // results of the saved baseline not yet written to disk
+	flushed     time.Time         // time of the last checkpoint
+	out         io.Writer         // where the report is written, the standard output if nil
 }
 
 // Run executes benchmarks with the given configuration
 func Run(fn func(*B), opts ...Option) {
-	cfg := defaultConfig()
+	runner, closeOutput, err := newRunner(defaultConfig(), opts, os.Stdout)
+	switch {
+	case errors.Is(err, flag.ErrHelp):
+		return
+	case err != nil:
+		fmt.Printf("Error: %v\n", err)
+		return
+	}
+	defer closeOutput()
 
-	// Apply flags first so user options can override
-	initFlags(&cfg)
+	// Write pending results even if a benchmark panics
+	defer runner.flush()
+
+	runner.estimateComparisons()
+	runner.printHeader()
+	fn(runner)
+	runner.flush()
+	runner.printSummary()
 
+	if runner.power {
+		runner.printPower()
+	}
+}
+
+// newRunner configures a runner from the options in code, the config file, the
+// environment and the flags, then opens its results file and report. It returns
+// flag.ErrHelp when help was requested, after printing it to w.
+func newRunner(cfg config, opts []Option, w io.Writer) (*B, func(), error) {
 	for _, opt := range opts {
 		opt(&cfg)
 	}
+
+	// The config file, environment and flags take precedence over the options in code
+	if err := loadConfig(&cfg, os.LookupEnv); err != nil {
+		return nil, nil, err
+	}
+
+	help, err := initFlags(&cfg, os.Args[1:], w)
+	switch {
+	case help:
+		return nil, nil, flag.ErrHelp
+	case err != nil:
+		return nil, nil, err
+	}
 	cfg.normalize()
 
+	if cfg.paired && cfg.method != MethodBCa {
+		fmt.Println("Warning: paired comparisons only apply to the bca method, the reference is compared as independent samples")
+	}
+
 	runner := &B{config: cfg}
-	runner.printHeader()
-	fn(runner)
+	if err := runner.open(); err != nil {
+		return nil, nil, err
+	}
+
+	closeOutput, err := runner.openOutput()
+	if err != nil {
+		return nil, nil, err
+	}
+	return runner, closeOutput, nil
 }
 
-// printHeader prints the table header
+// printHeader prints the table header, which the JSON report does not have.
 func (r *B) printHeader() {
+	if r.format == FormatJSON {
+		return
+	}
+
+	r.printRow("name", "time/op", "ops/s", "allocs/op", "bytes/op", "vs prev", "vs ref")
+	r.printRow("--------------------", "------------", "------------", "------------", "------------", "------------------", "------------------")
+}
+
+// printRow prints a table row, leaving out the last "vs ref" column unless
+// reference comparisons are shown.
+func (r *B) printRow(columns ...any) {
 	if r.showRef {
-		fmt.Printf(r.tableFmt, "name", "time/op", "ops/s", "allocs/op", "vs prev", "vs ref")
-		fmt.Printf(r.tableFmt, "--------------------", "------------", "------------", "------------", "------------------", "------------------")
-	} else {
-		fmt.Printf("%-20s %-12s %-12s %-12s %-18s\n", "name", "time/op", "ops/s", "allocs/op", "vs prev")
-		fmt.Printf("%-20s %-12s %-12s %-12s %-18s\n", "--------------------", "------------", "------------", "------------", "------------------")
+		fmt.Fprintf(r.writer(), r.tableFmt, columns...)
+		return
 	}
+
+	fmt.Fprintf(r.writer(), prevTableFmt, columns[:len(columns)-1]...)
 }
 
-// shouldRun checks if a benchmark matches the filter
+// shouldRun checks if a benchmark matches the prefix filter and the patterns.
+// Invalid patterns, which are reported when the suite starts, match nothing.
 func (r *B) shouldRun(name string) bool {
-	if r.filter == "" {
-		return true
+	if !strings.HasPrefix(name, r.filter) {
+		return false
 	}
-	return strings.HasPrefix(name, r.filter)
+
+	if r.matcher == nil {
+		m, err := newMatcher(&r.config)
+		if err != nil {
+			return false
+		}
+		r.matcher = m
+	}
+	return r.matcher.match(name)
+}
+
+// measurements holds the per-operation samples collected for a function
+type measurements struct {
+	timing []float64 // nanoseconds per operation
+	allocs []float64 // allocations per operation
+	bytes  []float64 // bytes allocated per operation
+}
+
+func newMeasurements(n int) measurements {
+	return measurements{
+		timing: make([]float64, 0, n),
+		allocs: make([]float64, 0, n),
+		bytes:  make([]float64, 0, n),
+	}
+}
+
+func (m *measurements) append(nsPerOp, allocsPerOp, bytesPerOp float64) {
+	m.timing = append(m.timing, nsPerOp)
+	m.allocs = append(m.allocs, allocsPerOp)
+	m.bytes = append(m.bytes, bytesPerOp)
 }
 
 // benchmark runs a function repeatedly and returns performance samples
-func (r *B) benchmark(fn func(op int) int) (timing []float64, allocs []float64) {
-	timing = make([]float64, 0, r.samples)
-	allocs = make([]float64, 0, r.samples)
+func (r *B) benchmark(fn func(op int) int) measurements {
+	for i := 0; i < r.warmup; i++ {
+		r.sample(fn)
+	}
 
+	out := newMeasurements(r.samples)
 	for i := 0; i < r.samples; i++ {
-		nsPerOp, allocsPerOp := r.sample(fn)
-		timing = append(timing, nsPerOp)
-		allocs = append(allocs, allocsPerOp)
+		out.append(r.sample(fn))
 	}
-	return timing, allocs
+	return out
 }
 
-func (r *B) benchmarkPair(ourFn, refFn func(op int) int) (ourTiming, ourAllocs, refTiming, refAllocs []float64) {
-	ourTiming = make([]float64, 0, r.samples)
-	ourAllocs = make([]float64, 0, r.samples)
-	refTiming = make([]float64, 0, r.samples)
-	refAllocs = make([]float64, 0, r.samples)
+func (r *B) benchmarkPair(ourFn, refFn func(op int) int) (ours, refs measurements) {
+	for i := 0; i < r.warmup; i++ {
+		r.sample(ourFn)
+		r.sample(refFn)
+	}
 
+	ours = newMeasurements(r.samples)
+	refs = newMeasurements(r.samples)
 	for i := 0; i < r.samples; i++ {
-		var ourNS, ourAlloc, refNS, refAlloc float64
 		if i%2 == 0 {
-			ourNS, ourAlloc = r.sample(ourFn)
-			refNS, refAlloc = r.sample(refFn)
+			ours.append(r.sample(ourFn))
+			refs.append(r.sample(refFn))
 		} else {
-			refNS, refAlloc = r.sample(refFn)
-			ourNS, ourAlloc = r.sample(ourFn)
+			refs.append(r.sample(refFn))
+			ours.append(r.sample(ourFn))
 		}
-
-		ourTiming = append(ourTiming, ourNS)
-		ourAllocs = append(ourAllocs, ourAlloc)
-		refTiming = append(refTiming, refNS)
-		refAllocs = append(refAllocs, refAlloc)
 	}
-	return ourTiming, ourAllocs, refTiming, refAllocs
+	return ours, refs
 }
 
-func (r *B) sample(fn func(op int) int) (nsPerOp, allocsPerOp float64) {
+func (r *B) sample(fn func(op int) int) (nsPerOp, allocsPerOp, bytesPerOp float64) {
 	// Force GC to get clean allocation measurements.
 	runtime.GC()
 	runtime.GC()
@@ -144,7 +248,8 @@ func (r *B) sample(fn func(op int) int) (nsPerOp, allocsPerOp float64) {
 	runtime.ReadMemStats(&m2)
 
 	return float64(elapsed.Nanoseconds()) / float64(ops),
-		float64(m2.Mallocs-m1.Mallocs) / float64(ops)
+		float64(m2.Mallocs-m1.Mallocs) / float64(ops),
+		float64(m2.TotalAlloc-m1.TotalAlloc) / float64(ops)
 }
 
 func addOps(total, n int) int {
@@ -171,6 +276,57 @@ func (r *B) Run(name string, ourFn func(i int), refFn ...func(i int)) Report {
 	return r.run(name, func(i int) int { ourFn(i); return 1 }, refWrapped)
 }
 
+// Scope runs benchmarks with options overriding the suite config.
+type Scope struct {
+	b    *B
+	opts []Option
+}
+
+// With returns a scope which runs benchmarks with the given options, overriding
+// the suite config for those benchmarks only. Only the samples, duration,
+// threshold, confidence, warmup and tags can be overridden, while the other
+// options are ignored. The environment variables and flags still take precedence.
+func (r *B) With(opts ...Option) Scope {
+	return Scope{b: r, opts: opts}
+}
+
+// Run executes a benchmark with the options of the scope.
+func (s Scope) Run(name string, ourFn func(i int), refFn ...func(i int)) Report {
+	defer s.apply()()
+	return s.b.Run(name, ourFn, refFn...)
+}
+
+// RunN executes a benchmark returning its operation count with the options of the scope.
+func (s Scope) RunN(name string, ourFn func(i int) int, refFn ...func(i int) int) Report {
+	defer s.apply()()
+	return s.b.RunN(name, ourFn, refFn...)
+}
+
+// apply applies the options of the scope, returning a function which restores
+// the suite config.
+func (s Scope) apply() (restore func()) {
+	suite := s.b.config
+	scoped := suite
+	for _, opt := range s.opts {
+		opt(&scoped)
+	}
+
+	// Tags add up, while the environment and flags override the other settings
+	tags := scoped.tags
+	for _, opt := range suite.pinned {
+		opt(&scoped)
+	}
+	scoped.normalize()
+
+	s.b.samples = scoped.samples
+	s.b.duration = scoped.duration
+	s.b.threshold = scoped.threshold
+	s.b.confidence = scoped.confidence
+	s.b.warmup = scoped.warmup
+	s.b.tags = tags
+	return func() { s.b.config = suite }
+}
+
 // RunN executes a benchmark where each iteration may return the number of
 // operations performed. This allows amortizing expensive setup or batching.
 func (r *B) RunN(name string, ourFn func(i int) int, refFn ...func(i int) int) Report {
@@ -187,62 +343,178 @@ func (r *B) run(name string, ourFn func(int) int, refFn func(int) int) (report R
 		return
 	}
 
-	// Load previous results for delta comparison
-	prevResults := r.loadResults()
+	// Settings overridden for this benchmark only
+	defer r.applyOverrides(name)()
 
-	var ourSamples, ourAllocs, refSamples []float64
-	if refFn != nil {
-		ourSamples, ourAllocs, refSamples, _ = r.benchmarkPair(ourFn, refFn)
-	} else {
-		ourSamples, ourAllocs = r.benchmark(ourFn)
+	// Previous results of the compared baseline, loaded once per suite
+	prevResults := r.previous().get(r.baseline)
+
+	// Collect samples, re-running when they drift over the course of the run
+	var ours, refs measurements
+	unstable := false
+	for attempt := 0; ; attempt++ {
+		if refFn != nil {
+			ours, refs = r.benchmarkPair(ourFn, refFn)
+		} else {
+			ours = r.benchmark(ourFn)
+		}
+
+		unstable = hasTrend(ours.timing, r.confidence/100.0)
+		if !unstable || attempt >= r.retries {
+			break
+		}
 	}
-	nsPerOp := median(ourSamples)
+
+	nsPerOp := median(ours.timing)
 	opsPerSec := 1e9 / nsPerOp
 
 	// Calculate average allocations per operation
-	avgAllocsPerOp := median(ourAllocs)
+	avgAllocsPerOp := median(ours.allocs)
+	avgBytesPerOp := median(ours.bytes)
 
 	// Create result
 	result := Result{
 		Name:      name,
-		Samples:   ourSamples,
-		Allocs:    ourAllocs,
+		Samples:   ours.timing,
+		Allocs:    ours.allocs,
+		Bytes:     ours.bytes,
+		Outliers:  countOutliers(ours.timing, r.fence),
+		Unstable:  unstable,
+		Tags:      slices.Clone(r.tags),
 		Timestamp: time.Now().Unix(),
 	}
 
 	// Calculate delta vs previous run
 	prevResult, exists := prevResults[name]
 	vsPrev := "new"
-	allocsChange := allocUnknown
+	allocsChange, bytesChange := allocUnknown, allocUnknown
+	var prevReport, refReport *Report
 	if exists {
-		report = bcaWithSeed(prevResult.Samples, ourSamples, r.confidence/100.0, r.bootstrap, r.threshold, r.seed)
+		report = r.compare(prevResult.Samples, ours.timing)
+		report.Allocs, allocsChange = r.compareAllocs(prevResult.Allocs, ours.allocs)
+		report.Bytes, bytesChange = r.compareAllocs(prevResult.Bytes, ours.bytes)
+		prevReport = &report
+		r.prevReports = append(r.prevReports, report)
 		vsPrev = r.formatComparison(report)
-		allocsChange = compareAllocs(prevResult.Allocs, ourAllocs)
-		if r.t != nil && report.Significant && report.Delta > 0 {
-			r.t.Errorf("%s has a performance regression of %s", name, vsPrev)
-		}
+		r.checkRegression(name, report, vsPrev)
 	}
 
 	// Calculate vs reference if provided
 	vsRef := ""
 	if refFn != nil {
-		report := bcaWithSeed(refSamples, ourSamples, r.confidence/100.0, r.bootstrap, r.threshold, r.seed)
+		report := r.compareRef(refs.timing, ours.timing)
+		refReport = &report
+		r.refReports = append(r.refReports, report)
 		vsRef = r.formatComparison(report)
 	}
 
 	// Format and display result
-	fmt.Printf(r.tableFmt, name,
-		formatTime(nsPerOp),
-		formatOps(opsPerSec),
-		formatAllocsWithChange(avgAllocsPerOp, allocsChange),
-		vsPrev,
-		vsRef)
+	switch r.format {
+	case FormatJSON:
+		r.printJSON(newResultRecord(result, prevReport, refReport))
+	default:
+		r.printRow(name,
+			formatTimeWithWarnings(nsPerOp, result),
+			formatOps(opsPerSec),
+			formatAllocsWithChange(avgAllocsPerOp, allocsChange),
+			formatBytesWithChange(avgBytesPerOp, bytesChange),
+			vsPrev,
+			vsRef)
+	}
 
 	// Save result incrementally
 	r.saveResult(result)
 	return
 }
 
+// checkRegression fails the test of Assert on a significant regression. The
+// interval of drifting samples cannot be trusted, so it is only logged.
+func (r *B) checkRegression(name string, report Report, change string) {
+	switch {
+	case r.t == nil || !report.Significant || report.Delta <= 0:
+	case report.Unstable:
+		r.t.Logf("%s shows a regression of %s, but its samples drift", name, change)
+	default:
+		r.t.Errorf("%s has a performance regression of %s", name, change)
+	}
+}
+
+// compare runs the configured inference method on two timing sample sets.
+func (r *B) compare(control, variant []float64) Report {
+	return r.compareWith(control, variant, r.threshold)
+}
+
+// compareWith runs the configured inference method on two sample sets with the
+// given practical threshold, in percent.
+func (r *B) compareWith(control, variant []float64, threshold float64) (report Report) {
+	unstable := hasTrend(control, r.confidence/100.0) || hasTrend(variant, r.confidence/100.0)
+	if r.trim {
+		control = trimOutliers(control, r.fence)
+		variant = trimOutliers(variant, r.fence)
+	}
+
+	confidence, comparisons := r.adjustedConfidence()
+	switch r.method {
+	case MethodMannWhitney:
+		report = mannWhitney(control, variant, confidence, threshold)
+	default:
+		report = bcaWithSeed(control, variant, confidence, r.bootstrap, threshold, r.seed)
+	}
+
+	report.Comparisons = comparisons
+	report.Unstable = unstable
+	return
+}
+
+// compareRef compares samples against the reference implementation, which are
+// naturally paired when the paired mode is enabled. Only the bootstrap has a
+// paired mode, so the rank test always compares them as independent samples.
+func (r *B) compareRef(control, variant []float64) (report Report) {
+	if !r.paired || r.method != MethodBCa || len(control) != len(variant) {
+		return r.compare(control, variant)
+	}
+
+	unstable := hasTrend(control, r.confidence/100.0) || hasTrend(variant, r.confidence/100.0)
+	if r.trim {
+		control, variant = trimPairs(control, variant, r.fence)
+	}
+
+	confidence, comparisons := r.adjustedConfidence()
+	report = bcaPaired(control, variant, confidence, r.bootstrap, r.threshold, r.seed)
+	report.Comparisons = comparisons
+	report.Unstable = unstable
+	return
+}
+
+// adjustedConfidence returns the per-comparison confidence level after applying
+// the configured multiple-comparison correction.
+func (r *B) adjustedConfidence() (float64, int) {
+	confidence := r.confidence / 100.0
+	switch r.correction {
+	case CorrectionBonferroni:
+		return bonferroni(confidence, r.comparisons), max(1, r.comparisons)
+	default:
+		return confidence, 1
+	}
+}
+
+// estimateComparisons derives the number of comparisons in the suite from the
+// previous results of the baseline matching the filter, unless it was set explicitly.
+// Comparisons against the reference are not known before the suite runs, so the
+// estimate covers only those against the previous results, and is at least one.
+func (r *B) estimateComparisons() {
+	if r.correction == CorrectionNone || r.comparisons > 0 {
+		return
+	}
+
+	for name := range r.previous().get(r.baseline) {
+		if r.shouldRun(name) {
+			r.comparisons++
+		}
+	}
+	r.comparisons = max(1, r.comparisons)
+}
+
 // Assert runs benchmarks in dry-run mode and fails the test if performance regresses.
 // It is skipped when testing is run with -short.
 func Assert(t testing.TB, fn func(*B), opts ...Option) {
@@ -254,14 +526,23 @@ func Assert(t testing.TB, fn func(*B), opts ...Option) {
 
 	cfg := defaultConfig()
 	cfg.dryRun = true
-
-	initFlags(&cfg)
-	for _, opt := range opts {
-		opt(&cfg)
+	runner, closeOutput, err := newRunner(cfg, opts, io.Discard)
+	switch {
+	case errors.Is(err, flag.ErrHelp):
+		return
+	case err != nil:
+		t.Errorf("%v", err)
+		return
 	}
-	cfg.normalize()
+	defer closeOutput()
+
+	// Write pending results when dry run is turned off, even if a benchmark panics
+	defer runner.flush()
+
+	runner.t = t
 
-	runner := &B{config: cfg, t: t}
+	runner.estimateComparisons()
 	runner.printHeader()
 	fn(runner)
+	runner.printSummary()
 }
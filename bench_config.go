@@ -0,0 +1,329 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root
+
+package bench
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// configFiles are the names of the config files discovered in the working
+// directory, in order of preference.
+var configFiles = []string{"bench.yaml", "bench.yml", "bench.json"}
+
+// perBenchmark lists the settings which can be overridden for a single benchmark.
+// Unlike a Scope, which only carries the sampling settings of its benchmarks, an
+// override replaces the config for the whole run of the benchmark, so settings
+// of its comparisons such as the method or the bootstrap are honoured as well.
+var perBenchmark = []string{
+	"samples", "duration", "retries", "paired", "confidence", "threshold",
+	"alloc-threshold", "bootstrap", "seed", "method", "fence", "trim", "warmup", "tag",
+}
+
+// override applies options to the benchmarks matching its patterns.
+type override struct {
+	matcher *matcher
+	options []Option
+}
+
+// loadConfig applies the optional config file, followed by the BENCH_*
+// environment variables, which take precedence over the options in code.
+func loadConfig(c *config, getenv func(string) (string, bool)) error {
+	filename, settings, err := findConfigFile(c.filename, getenv)
+	if err != nil {
+		return err
+	}
+
+	if settings != nil {
+		if err := applyConfigFile(c, filename, settings); err != nil {
+			return err
+		}
+	}
+	return loadEnv(c, getenv)
+}
+
+// findConfigFile returns the settings of the config file named by BENCH_CONFIG,
+// or of the first config file found in the working directory. Discovered files
+// are skipped unless they declare themselves with a top-level "bench" key, and
+// the results file is never read, so a bench.json holding results is left alone.
+func findConfigFile(results string, getenv func(string) (string, bool)) (string, map[string]any, error) {
+	if filename, ok := getenv("BENCH_CONFIG"); ok && filename != "" {
+		settings, found, err := readConfigFile(filename)
+		switch {
+		case err != nil:
+			return "", nil, err
+		case !found:
+			return "", nil, fmt.Errorf("bench: %s has no top-level %q key", filename, configKey)
+		}
+		return filename, settings, nil
+	}
+
+	for _, filename := range configFiles {
+		if sameFile(filename, results) {
+			continue
+		}
+
+		settings, found, err := readConfigFile(filename)
+		switch {
+		case errors.Is(err, fs.ErrNotExist):
+			continue
+		case errors.Is(err, errNotConfig):
+			continue // A JSON file we cannot parse is not known to be a config file
+		case err != nil:
+			return "", nil, err
+		case found:
+			return filename, settings, nil
+		}
+	}
+	return "", nil, nil
+}
+
+// configKey is the top-level key holding the settings of a config file.
+const configKey = "bench"
+
+// errNotConfig is returned for a JSON file which cannot be parsed as a config
+// file, as it may as well hold something else such as benchmark results.
+var errNotConfig = errors.New("bench: not a config file")
+
+// readConfigFile reads a YAML or JSON file and returns the settings held by its
+// top-level "bench" key, reporting whether the key was found. The remaining
+// top-level keys are left for other tools.
+func readConfigFile(filename string) (map[string]any, bool, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, false, fmt.Errorf("bench: %w", err)
+	}
+
+	var section any
+	if filepath.Ext(filename) == ".json" {
+		var doc map[string]json.RawMessage
+		if err := json.Unmarshal(data, &doc); err != nil {
+			return nil, false, fmt.Errorf("%w: unable to parse %s: %w", errNotConfig, filename, err)
+		}
+
+		raw, ok := doc[configKey]
+		if !ok {
+			return nil, false, nil
+		}
+		err = json.Unmarshal(raw, &section)
+	} else {
+		var doc map[string]any
+		if err := yaml.Unmarshal(data, &doc); err != nil {
+			return nil, false, fmt.Errorf("bench: unable to parse %s: %w", filename, err)
+		}
+
+		var ok bool
+		if section, ok = doc[configKey]; !ok {
+			return nil, false, nil
+		}
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("bench: unable to parse %s: %w", filename, err)
+	}
+
+	switch settings := section.(type) {
+	case nil:
+		return map[string]any{}, true, nil
+	case map[string]any:
+		return settings, true, nil
+	default:
+		return nil, false, fmt.Errorf("bench: %s: %q must be a map", filename, configKey)
+	}
+}
+
+// sameFile reports whether the two names refer to the same path.
+func sameFile(a, b string) bool {
+	a, errA := filepath.Abs(a)
+	b, errB := filepath.Abs(b)
+	return errA == nil && errB == nil && a == b
+}
+
+// loadEnv applies the BENCH_* environment variables named after the flags,
+// e.g. BENCH_SAMPLES for -bench.samples and BENCH_DRY_RUN for -bench.n.
+func loadEnv(c *config, getenv func(string) (string, bool)) error {
+	var opts []Option
+	flags := newFlagSet(&opts)
+
+	var err error
+	flags.VisitAll(func(f *flag.Flag) {
+		if v, ok := getenv(envName(f.Name)); ok && err == nil {
+			if setErr := flags.Set(f.Name, v); setErr != nil {
+				err = fmt.Errorf("bench: invalid value %q for %s: %w", v, envName(f.Name), setErr)
+			}
+		}
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+	c.pinned = append(c.pinned, opts...)
+	return nil
+}
+
+// envName returns the environment variable corresponding to a flag.
+func envName(flag string) string {
+	if flag == "n" {
+		return "BENCH_DRY_RUN"
+	}
+	return "BENCH_" + strings.ToUpper(strings.ReplaceAll(flag, "-", "_"))
+}
+
+// applyConfigFile applies the settings of a config file. Its keys are named
+// after the flags, along with a "benchmarks" list of per-benchmark overrides,
+// each selecting benchmarks with "match" regular expressions or "glob" patterns.
+func applyConfigFile(c *config, filename string, settings map[string]any) error {
+	benchmarks, err := parseOverrides(settings["benchmarks"])
+	if err != nil {
+		return fmt.Errorf("bench: %s: %w", filename, err)
+	}
+	delete(settings, "benchmarks")
+
+	opts, err := parseSettings(settings, nil)
+	if err != nil {
+		return fmt.Errorf("bench: %s: %w", filename, err)
+	}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+	c.overrides = append(c.overrides, benchmarks...)
+	return nil
+}
+
+// parseOverrides parses the list of per-benchmark overrides.
+func parseOverrides(value any) ([]override, error) {
+	if value == nil {
+		return nil, nil
+	}
+
+	list, ok := value.([]any)
+	if !ok {
+		return nil, errors.New("benchmarks must be a list")
+	}
+
+	out := make([]override, 0, len(list))
+	for i, item := range list {
+		settings, ok := item.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("benchmarks[%d] must be a map", i)
+		}
+
+		var selector config
+		var err error
+		if selector.match, err = settingValues(settings["match"]); err != nil {
+			return nil, fmt.Errorf("benchmarks[%d]: %w", i, err)
+		}
+		if selector.glob, err = settingValues(settings["glob"]); err != nil {
+			return nil, fmt.Errorf("benchmarks[%d]: %w", i, err)
+		}
+		if len(selector.match)+len(selector.glob) == 0 {
+			return nil, fmt.Errorf("benchmarks[%d] requires a match or glob pattern", i)
+		}
+
+		m, err := newMatcher(&selector)
+		if err != nil {
+			return nil, err
+		}
+
+		delete(settings, "match")
+		delete(settings, "glob")
+		opts, err := parseSettings(settings, perBenchmark)
+		if err != nil {
+			return nil, fmt.Errorf("benchmarks[%d]: %w", i, err)
+		}
+		out = append(out, override{matcher: m, options: opts})
+	}
+	return out, nil
+}
+
+// parseSettings converts settings named after the flags to options, accepting
+// only the allowed names unless nil.
+func parseSettings(settings map[string]any, allowed []string) ([]Option, error) {
+	var opts []Option
+	flags := newFlagSet(&opts)
+
+	names := make([]string, 0, len(settings))
+	for name := range settings {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	for _, name := range names {
+		if flags.Lookup(name) == nil || (allowed != nil && !slices.Contains(allowed, name)) {
+			return nil, fmt.Errorf("unknown setting %q", name)
+		}
+
+		values, err := settingValues(settings[name])
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		for _, v := range values {
+			if err := flags.Set(name, v); err != nil {
+				return nil, fmt.Errorf("invalid value %q for %s: %w", v, name, err)
+			}
+		}
+	}
+	return opts, nil
+}
+
+// settingValues formats a scalar or a list of scalars as flag values.
+func settingValues(value any) ([]string, error) {
+	switch v := value.(type) {
+	case nil:
+		return nil, nil
+	case []any:
+		out := make([]string, 0, len(v))
+		for _, item := range v {
+			values, err := settingValues(item)
+			if err != nil || len(values) != 1 {
+				return nil, errors.New("lists must hold single values")
+			}
+			out = append(out, values...)
+		}
+		return out, nil
+	case map[string]any:
+		return nil, errors.New("unexpected map")
+	case float64:
+		return []string{strconv.FormatFloat(v, 'f', -1, 64)}, nil
+	default:
+		return []string{fmt.Sprint(v)}, nil
+	}
+}
+
+// applyOverrides applies the per-benchmark overrides matching the name to the
+// config, below the environment and flags, returning a function which restores
+// the suite config.
+func (r *B) applyOverrides(name string) (restore func()) {
+	suite := r.config
+	for _, o := range r.overrides {
+		if o.matcher.match(name) {
+			for _, opt := range o.options {
+				opt(&r.config)
+			}
+		}
+	}
+
+	// Tags add up, while the environment and flags override the other settings
+	tags := r.tags
+	for _, opt := range suite.pinned {
+		opt(&r.config)
+	}
+	r.tags = tags
+	r.normalize()
+
+	return func() { r.config = suite }
+}
@@ -0,0 +1,203 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root
+
+package bench
+
+import (
+	"io"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// envOf returns a lookup function over the given environment variables.
+func envOf(env map[string]string) func(string) (string, bool) {
+	return func(key string) (string, bool) {
+		v, ok := env[key]
+		return v, ok
+	}
+}
+
+func TestEnvName(t *testing.T) {
+	assert.Equal(t, "BENCH_SAMPLES", envName("samples"))
+	assert.Equal(t, "BENCH_ALLOC_THRESHOLD", envName("alloc-threshold"))
+	assert.Equal(t, "BENCH_DRY_RUN", envName("n"))
+}
+
+func TestLoadEnv(t *testing.T) {
+	cfg := defaultConfig()
+	WithSamples(10)(&cfg)
+
+	assert.NoError(t, loadEnv(&cfg, envOf(map[string]string{
+		"BENCH_SAMPLES":    "200",
+		"BENCH_CONFIDENCE": "99.99",
+		"BENCH_DRY_RUN":    "true",
+		"BENCH_METHOD":     "mannwhitney",
+		"OTHER_SAMPLES":    "5",
+	})))
+	assert.Equal(t, 200, cfg.samples)
+	assert.Equal(t, 99.99, cfg.confidence)
+	assert.True(t, cfg.dryRun)
+	assert.Equal(t, MethodMannWhitney, cfg.method)
+
+	// A false boolean turns off a setting enabled earlier
+	assert.NoError(t, loadEnv(&cfg, envOf(map[string]string{"BENCH_DRY_RUN": "false"})))
+	assert.False(t, cfg.dryRun)
+
+	err := loadEnv(&cfg, envOf(map[string]string{"BENCH_SAMPLES": "many"}))
+	assert.ErrorContains(t, err, "BENCH_SAMPLES")
+}
+
+func TestLoadConfigFile(t *testing.T) {
+	t.Chdir(t.TempDir())
+	assert.NoError(t, os.WriteFile("bench.yaml", []byte(`
+bench:
+  samples: 200
+  confidence: 99.5
+  duration: 20ms
+  skip: [large, huge]
+  benchmarks:
+    - match: ^slow
+      samples: 10
+      duration: 1s
+    - glob: [fast/*, quick/*]
+      threshold: 1e1
+`), 0644))
+
+	cfg := defaultConfig()
+	WithSamples(50)(&cfg)
+	assert.NoError(t, loadConfig(&cfg, envOf(map[string]string{"BENCH_CONFIDENCE": "95"})))
+
+	// The environment takes precedence over the file, which takes precedence over code
+	assert.Equal(t, 200, cfg.samples)
+	assert.Equal(t, 95.0, cfg.confidence)
+	assert.Equal(t, 20*time.Millisecond, cfg.duration)
+	assert.Equal(t, []string{"large", "huge"}, cfg.skip)
+	assert.Len(t, cfg.overrides, 2)
+
+	b := &B{config: cfg}
+	restore := b.applyOverrides("slow/a")
+	assert.Equal(t, 10, b.samples)
+	assert.Equal(t, time.Second, b.duration)
+	restore()
+	assert.Equal(t, 200, b.samples)
+
+	restore = b.applyOverrides("quick/a")
+	assert.Equal(t, 10.0, b.threshold)
+	assert.Equal(t, 200, b.samples)
+	restore()
+	assert.Equal(t, defaultThreshold, b.threshold)
+}
+
+func TestOverridesBelowEnvironment(t *testing.T) {
+	t.Chdir(t.TempDir())
+	assert.NoError(t, os.WriteFile("bench.yaml", []byte(`
+bench:
+  benchmarks:
+    - match: ^slow
+      samples: 10
+      threshold: 20
+      tag: slow
+`), 0644))
+
+	cfg := defaultConfig()
+	assert.NoError(t, loadConfig(&cfg, envOf(map[string]string{"BENCH_SAMPLES": "30", "BENCH_TAG": "ci"})))
+	_, err := initFlags(&cfg, []string{"-bench.threshold", "2"}, io.Discard)
+	assert.NoError(t, err)
+
+	// The environment and flags win over the override, while tags add up
+	b := &B{config: cfg}
+	restore := b.applyOverrides("slow/a")
+	assert.Equal(t, 30, b.samples)
+	assert.Equal(t, 2.0, b.threshold)
+	assert.Equal(t, []string{"ci", "slow"}, b.tags)
+	restore()
+	assert.Equal(t, []string{"ci"}, b.tags)
+}
+
+func TestLoadConfigFileJSON(t *testing.T) {
+	t.Chdir(t.TempDir())
+
+	// A results file named bench.json is not a config file, whatever its version
+	for _, content := range []string{`{"version": 99, "results": {}}`, `{"version": 2, "res`} {
+		assert.NoError(t, os.WriteFile("bench.json", []byte(content), 0644))
+		cfg := defaultConfig()
+		assert.NoError(t, loadConfig(&cfg, envOf(nil)), content)
+		assert.Equal(t, defaultSamples, cfg.samples)
+	}
+
+	assert.NoError(t, os.WriteFile("bench.json", []byte(`{"bench": {"samples": 1000000, "benchmarks": [{"match": "a", "trim": true}]}}`), 0644))
+	cfg := defaultConfig()
+	assert.NoError(t, loadConfig(&cfg, envOf(nil)))
+	assert.Equal(t, 1000000, cfg.samples)
+	assert.Len(t, cfg.overrides, 1)
+
+	// The results file is never read as a config file
+	cfg = defaultConfig()
+	WithFile("./bench.json")(&cfg)
+	assert.NoError(t, loadConfig(&cfg, envOf(nil)))
+	assert.Equal(t, defaultSamples, cfg.samples)
+}
+
+func TestLoadConfigFileUndeclared(t *testing.T) {
+	t.Chdir(t.TempDir())
+
+	// Files without a top-level "bench" key are skipped when discovered
+	assert.NoError(t, os.WriteFile("bench.yaml", []byte("samples: 5\n"), 0644))
+	assert.NoError(t, os.WriteFile("bench.yml", []byte("other: 1\nbench:\n  samples: 7\n"), 0644))
+	cfg := defaultConfig()
+	assert.NoError(t, loadConfig(&cfg, envOf(nil)))
+	assert.Equal(t, 7, cfg.samples)
+
+	// But not when named explicitly
+	err := loadConfig(&cfg, envOf(map[string]string{"BENCH_CONFIG": "bench.yaml"}))
+	assert.ErrorContains(t, err, `no top-level "bench" key`)
+}
+
+func TestLoadConfigFileErrors(t *testing.T) {
+	t.Chdir(t.TempDir())
+
+	for _, content := range []string{
+		"bench: {samples: [",
+		"bench: 1",
+		"bench: {unknown: 1}",
+		"bench: {samples: many}",
+		"bench: {samples: {a: 1}}",
+		"bench: {benchmarks: 1}",
+		"bench: {benchmarks: [1]}",
+		"bench: {benchmarks: [{samples: 10}]}",
+		"bench: {benchmarks: [{match: '(', samples: 10}]}",
+		"bench: {benchmarks: [{match: a, file: x.json}]}",
+	} {
+		assert.NoError(t, os.WriteFile("bench.yml", []byte(content), 0644))
+		cfg := defaultConfig()
+		assert.Error(t, loadConfig(&cfg, envOf(nil)), content)
+	}
+
+	cfg := defaultConfig()
+	assert.Error(t, loadConfig(&cfg, envOf(map[string]string{"BENCH_CONFIG": "missing.yaml"})))
+}
+
+func TestRunWithConfigFile(t *testing.T) {
+	t.Chdir(t.TempDir())
+	assert.NoError(t, os.WriteFile("bench.yaml", []byte(`
+bench:
+  file: results.json
+  duration: 1ms
+  benchmarks:
+    - match: ^slow$
+      samples: 3
+`), 0644))
+
+	Run(func(b *B) {
+		b.Run("slow", func(i int) {})
+		b.Run("fast", func(i int) {})
+	}, WithSamples(5))
+
+	loaded, err := jsonCodec{}.Load("results.json")
+	assert.NoError(t, err)
+	assert.Len(t, loaded[LatestBaseline]["slow"].Samples, 3)
+	assert.Len(t, loaded[LatestBaseline]["fast"].Samples, 5)
+}
@@ -0,0 +1,244 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root
+
+package bench
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	methodNames     = map[string]Method{"bca": MethodBCa, "mannwhitney": MethodMannWhitney}
+	correctionNames = map[string]Correction{"none": CorrectionNone, "bonferroni": CorrectionBonferroni}
+	fenceNames      = map[string]Fence{"tukey": FenceTukey, "mad": FenceMAD}
+	recoveryNames   = map[string]Recovery{"fail": RecoverFail, "warn": RecoverWarn, "rename": RecoverRename}
+	formatNames     = map[string]Format{"table": FormatTable, "json": FormatJSON}
+)
+
+// flagPrefix namespaces the flags on the command line, as "go test" does with
+// "-test.", so that they do not clash with the flags of the program.
+const flagPrefix = "bench."
+
+// newFlagSet defines a flag for every option. Parsing the flags appends the
+// options of the flags which are set, so unset flags leave the config as is.
+func newFlagSet(opts *[]Option) *flag.FlagSet {
+	fs := flag.NewFlagSet("bench", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	add := func(opt Option) { *opts = append(*opts, opt) }
+
+	// Results file
+	fs.Func("file", "results `file`, whose extension selects the format (default bench.gob)", func(v string) error {
+		add(WithFile(v))
+		return nil
+	})
+	fs.Func("codec", "format of the results file, by `extension` (e.g. .json, .gob.gz)", func(v string) error {
+		codec, ok := lookupCodec("." + strings.TrimPrefix(v, "."))
+		if !ok {
+			return fmt.Errorf("unknown codec %q", v)
+		}
+		add(WithCodec(codec))
+		return nil
+	})
+	fs.Func("recovery", "policy for unreadable results files: "+keys(recoveryNames), enumFlag(recoveryNames, WithRecovery, add))
+	fs.Func("checkpoint", "`interval` between writes of the results file (default 5s)", durationFlag(WithCheckpoint, add))
+	fs.Func("baseline", "compare against the named `baseline` instead of the latest run", func(v string) error {
+		add(WithBaseline(v))
+		return nil
+	})
+	fs.Func("save-baseline", "save results into the named `baseline` instead of the latest run", func(v string) error {
+		add(WithSaveBaseline(v))
+		return nil
+	})
+	fs.BoolFunc("n", "dry run - do not update the results file", boolFlag(func(c *config, v bool) { c.dryRun = v }, add))
+
+	// Report
+	fs.Func("format", "report format: "+keys(formatNames), enumFlag(formatNames, WithFormat, add))
+	fs.Func("output", "write the report to this `file` instead of the standard output", func(v string) error {
+		add(WithOutput(v))
+		return nil
+	})
+
+	// Benchmark selection
+	fs.Func("filter", "run only benchmarks starting with this `prefix`", func(v string) error {
+		add(WithFilter(v))
+		return nil
+	})
+	fs.Func("bench", "run only benchmarks matching this `regexp`, may be repeated", func(v string) error {
+		add(WithMatch(v))
+		return nil
+	})
+	fs.Func("glob", "run only benchmarks matching this glob `pattern`, may be repeated", func(v string) error {
+		add(WithGlob(v))
+		return nil
+	})
+	fs.Func("skip", "skip benchmarks matching this `regexp`, may be repeated", func(v string) error {
+		add(WithSkip(v))
+		return nil
+	})
+
+	// Sampling
+	fs.Func("samples", "`number` of samples per benchmark (default 100)", intFlag(WithSamples, add))
+	fs.Func("duration", "`duration` of each sample (default 10ms)", durationFlag(WithDuration, add))
+	fs.Func("warmup", "`number` of samples discarded before measuring", intFlag(WithWarmup, add))
+	fs.Func("tag", "`tag` stored with the results, may be repeated", func(v string) error {
+		add(WithTags(v))
+		return nil
+	})
+	fs.Func("retries", "`number` of re-runs when samples drift", intFlag(WithRetries, add))
+	fs.BoolFunc("ref", "show the comparison against the reference implementation", boolFlag(func(c *config, v bool) { c.showRef = v }, add))
+	fs.BoolFunc("paired", "compare against the reference with a paired bootstrap", boolFlag(func(c *config, v bool) { c.paired = v }, add))
+
+	// Inference
+	fs.Func("confidence", "confidence `level` in percent (default 99.9)", floatFlag(WithConfidence, add))
+	fs.Func("threshold", "minimum practical timing change in `percent` (default 5)", floatFlag(WithThreshold, add))
+	fs.Func("alloc-threshold", "minimum practical allocation change in `percent` (default 5)", floatFlag(WithAllocThreshold, add))
+	fs.Func("bootstrap", "`number` of bootstrap resamples (default 100000)", intFlag(WithBootstrap, add))
+	fs.Func("seed", "`seed` mixed into the bootstrap random number generator", func(v string) error {
+		seed, err := strconv.ParseUint(v, 10, 64)
+		if err != nil {
+			return errors.New("invalid unsigned integer")
+		}
+		add(WithSeed(seed))
+		return nil
+	})
+	fs.Func("method", "inference method: "+keys(methodNames), enumFlag(methodNames, WithMethod, add))
+	fs.Func("correction", "multiple-comparison correction: "+keys(correctionNames), enumFlag(correctionNames, WithCorrection, add))
+	fs.Func("comparisons", "`number` of comparisons the correction accounts for", intFlag(WithComparisons, add))
+	fs.Func("fence", "outlier fence: "+keys(fenceNames), enumFlag(fenceNames, WithFence, add))
+	fs.BoolFunc("trim", "exclude outliers before comparing samples", boolFlag(func(c *config, v bool) { c.trim = v }, add))
+	fs.BoolFunc("power", "print power analysis after the run", boolFlag(func(c *config, v bool) { c.power = v }, add))
+	return fs
+}
+
+// initFlags applies the flags set on the command line to the config, taking
+// precedence over the options given in code. Only the arguments starting with
+// the "-bench." prefix are bench flags, others such as those of "go test" or of
+// the program are ignored. It reports whether help was requested, after
+// printing it to w.
+func initFlags(c *config, args []string, w io.Writer) (help bool, err error) {
+	var opts []Option
+	fs := newFlagSet(&opts)
+
+	// Pick only the flags we know about, along with their values
+	ours := []string{}
+	for i := 0; i < len(args); i++ {
+		name, _, hasValue := strings.Cut(strings.TrimLeft(args[i], "-"), "=")
+		name, namespaced := strings.CutPrefix(name, flagPrefix)
+		switch {
+		case !strings.HasPrefix(args[i], "-") || !namespaced:
+			continue
+		case name == "h" || name == "help":
+			printFlags(fs, w)
+			return true, nil
+		case fs.Lookup(name) == nil:
+			return false, fmt.Errorf("bench: flag provided but not defined: -%s%s", flagPrefix, name)
+		}
+
+		ours = append(ours, strings.Replace(args[i], flagPrefix, "", 1))
+		if !hasValue && !isBoolFlag(fs.Lookup(name)) && i+1 < len(args) {
+			i++
+			ours = append(ours, args[i])
+		}
+	}
+
+	if err := fs.Parse(ours); err != nil {
+		return false, fmt.Errorf("bench: %w", err)
+	}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+	c.pinned = append(c.pinned, opts...)
+	return false, nil
+}
+
+// printFlags prints the help of every flag, under its name on the command line.
+func printFlags(fs *flag.FlagSet, w io.Writer) {
+	help := flag.NewFlagSet("bench", flag.ContinueOnError)
+	fs.VisitAll(func(f *flag.Flag) {
+		help.Var(f.Value, flagPrefix+f.Name, f.Usage)
+	})
+
+	fmt.Fprintln(w, "Usage of bench:")
+	help.SetOutput(w)
+	help.PrintDefaults()
+}
+
+func isBoolFlag(f *flag.Flag) bool {
+	b, ok := f.Value.(interface{ IsBoolFlag() bool })
+	return ok && b.IsBoolFlag()
+}
+
+// boolFlag sets a boolean setting to the parsed value, so that "-trim=false"
+// turns off a setting enabled in code.
+func boolFlag(set func(*config, bool), add func(Option)) func(string) error {
+	return func(v string) error {
+		enabled, err := strconv.ParseBool(v)
+		if err != nil {
+			return errors.New("invalid boolean")
+		}
+		add(func(c *config) { set(c, enabled) })
+		return nil
+	}
+}
+
+func intFlag(option func(int) Option, add func(Option)) func(string) error {
+	return func(v string) error {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return errors.New("invalid integer")
+		}
+		add(option(n))
+		return nil
+	}
+}
+
+func floatFlag(option func(float64) Option, add func(Option)) func(string) error {
+	return func(v string) error {
+		f, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return errors.New("invalid number")
+		}
+		add(option(f))
+		return nil
+	}
+}
+
+func durationFlag(option func(time.Duration) Option, add func(Option)) func(string) error {
+	return func(v string) error {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return errors.New("invalid duration")
+		}
+		add(option(d))
+		return nil
+	}
+}
+
+func enumFlag[T any](values map[string]T, option func(T) Option, add func(Option)) func(string) error {
+	return func(v string) error {
+		value, ok := values[strings.ToLower(v)]
+		if !ok {
+			return fmt.Errorf("must be one of %s", keys(values))
+		}
+		add(option(value))
+		return nil
+	}
+}
+
+// keys returns the sorted keys of an enumeration, separated by commas.
+func keys[T any](values map[string]T) string {
+	out := make([]string, 0, len(values))
+	for k := range values {
+		out = append(out, k)
+	}
+	slices.Sort(out)
+	return strings.Join(out, ", ")
+}
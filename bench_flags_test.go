@@ -0,0 +1,154 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root
+
+package bench
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInitFlagsPreservesExistingConfig(t *testing.T) {
+	cfg := config{dryRun: true, filter: "keep", samples: 10}
+	help, err := initFlags(&cfg, []string{"-test.v=true", "-test.run", "TestX"}, io.Discard)
+	assert.NoError(t, err)
+	assert.False(t, help)
+	assert.True(t, cfg.dryRun)
+	assert.Equal(t, "keep", cfg.filter)
+	assert.Equal(t, 10, cfg.samples)
+}
+
+func TestInitFlagsIgnoresProgramFlags(t *testing.T) {
+	cfg := defaultConfig()
+	help, err := initFlags(&cfg, []string{"-file", "program.txt", "-n", "-samples=5", "-h", "--help"}, io.Discard)
+	assert.NoError(t, err)
+	assert.False(t, help)
+	assert.Equal(t, defaultFilename, cfg.filename)
+	assert.False(t, cfg.dryRun)
+	assert.Equal(t, defaultSamples, cfg.samples)
+	assert.Empty(t, cfg.pinned)
+}
+
+func TestInitFlags(t *testing.T) {
+	cfg := defaultConfig()
+	WithSamples(10)(&cfg)
+	WithFile("code.json")(&cfg)
+
+	_, err := initFlags(&cfg, []string{
+		"-test.v", "positional",
+		"-bench.file", "flags.gob", "-bench.codec=.json.gz", "-bench.recovery", "rename", "-bench.checkpoint=1s",
+		"-bench.baseline", "main", "-bench.save-baseline=v2", "-bench.n",
+		"-bench.filter", "pre", "-bench.bench", "find", "-bench.bench=sort", "-bench.glob", "a/*", "-bench.skip", "large",
+		"-bench.samples", "50", "-bench.duration=20ms", "-bench.retries", "2", "-bench.ref", "-bench.paired=true",
+		"-bench.confidence", "95", "-bench.threshold", "2.5", "-bench.alloc-threshold=1", "-bench.bootstrap", "1000",
+		"-bench.seed", "7", "-bench.method", "MannWhitney", "-bench.correction", "bonferroni", "-bench.comparisons", "3",
+		"-bench.fence", "mad", "-bench.trim", "-bench.power=false", "-bench.warmup", "3", "-bench.tag", "ci", "-bench.tag=linux",
+	}, io.Discard)
+	assert.NoError(t, err)
+
+	// Flags take precedence over options in code
+	assert.Equal(t, "flags.gob", cfg.filename)
+	assert.Equal(t, jsonCodec{compress: true}, cfg.codec)
+	assert.Equal(t, RecoverRename, cfg.recovery)
+	assert.Equal(t, time.Second, cfg.checkpoint)
+	assert.Equal(t, "main", cfg.baseline)
+	assert.Equal(t, "v2", cfg.saveBaseline)
+	assert.True(t, cfg.dryRun)
+	assert.Equal(t, "pre", cfg.filter)
+	assert.Equal(t, []string{"find", "sort"}, cfg.match)
+	assert.Equal(t, []string{"a/*"}, cfg.glob)
+	assert.Equal(t, []string{"large"}, cfg.skip)
+	assert.Equal(t, 50, cfg.samples)
+	assert.Equal(t, 20*time.Millisecond, cfg.duration)
+	assert.Equal(t, 2, cfg.retries)
+	assert.True(t, cfg.showRef)
+	assert.True(t, cfg.paired)
+	assert.Equal(t, 95.0, cfg.confidence)
+	assert.Equal(t, 2.5, cfg.threshold)
+	assert.Equal(t, 1.0, cfg.allocThreshold)
+	assert.Equal(t, 1000, cfg.bootstrap)
+	assert.Equal(t, uint64(7), cfg.seed)
+	assert.Equal(t, MethodMannWhitney, cfg.method)
+	assert.Equal(t, CorrectionBonferroni, cfg.correction)
+	assert.Equal(t, 3, cfg.comparisons)
+	assert.Equal(t, FenceMAD, cfg.fence)
+	assert.True(t, cfg.trim)
+	assert.False(t, cfg.power)
+	assert.Equal(t, 3, cfg.warmup)
+	assert.Equal(t, []string{"ci", "linux"}, cfg.tags)
+}
+
+func TestInitFlagsDisableOptions(t *testing.T) {
+	cfg := defaultConfig()
+	for _, opt := range []Option{WithDryRun(), WithReference(), WithPaired(), WithTrim(), WithPower()} {
+		opt(&cfg)
+	}
+
+	_, err := initFlags(&cfg, []string{"-bench.n=false", "-bench.ref=false", "-bench.paired=false", "-bench.trim=false", "-bench.power=false"}, io.Discard)
+	assert.NoError(t, err)
+	assert.False(t, cfg.dryRun)
+	assert.False(t, cfg.showRef)
+	assert.False(t, cfg.paired)
+	assert.False(t, cfg.trim)
+	assert.False(t, cfg.power)
+}
+
+func TestInitFlagsReport(t *testing.T) {
+	cfg := defaultConfig()
+	_, err := initFlags(&cfg, []string{"-bench.format", "JSON", "-bench.output=report.jsonl"}, io.Discard)
+	assert.NoError(t, err)
+	assert.Equal(t, FormatJSON, cfg.format)
+	assert.Equal(t, "report.jsonl", cfg.output)
+
+	_, err = initFlags(&cfg, []string{"-bench.format", "xml"}, io.Discard)
+	assert.Error(t, err)
+}
+
+func TestInitFlagsErrors(t *testing.T) {
+	for _, args := range [][]string{
+		{"-bench.samples", "many"},
+		{"-bench.duration", "long"},
+		{"-bench.confidence=high"},
+		{"-bench.seed", "-1"},
+		{"-bench.method", "magic"},
+		{"-bench.codec", ".zip"},
+		{"-bench.trim=maybe"},
+		{"-bench.unknown"},
+	} {
+		cfg := defaultConfig()
+		_, err := initFlags(&cfg, args, io.Discard)
+		assert.Error(t, err, "%v", args)
+	}
+}
+
+func TestInitFlagsHelp(t *testing.T) {
+	var out bytes.Buffer
+	cfg := defaultConfig()
+	help, err := initFlags(&cfg, []string{"-bench.samples", "5", "-bench.help"}, &out)
+	assert.NoError(t, err)
+	assert.True(t, help)
+	assert.Contains(t, out.String(), "-bench.samples number")
+	assert.Contains(t, out.String(), "bca, mannwhitney")
+	assert.Equal(t, defaultSamples, cfg.samples)
+}
+
+func TestRunHelp(t *testing.T) {
+	ran := false
+	withArgs(t, "-bench.h")
+	Run(func(b *B) { ran = true })
+	assert.False(t, ran)
+}
+
+// withArgs replaces the command-line arguments for the duration of the test.
+func withArgs(t *testing.T, args ...string) {
+	oldArgs := os.Args
+	t.Cleanup(func() {
+		os.Args = oldArgs
+	})
+	os.Args = append([]string{"test"}, args...)
+}
@@ -4,9 +4,7 @@
 package bench
 
 import (
-	"flag"
-	"os"
-	"strings"
+	"slices"
 	"time"
 )
 
@@ -16,20 +14,95 @@ type Option func(*config)
 
 // config holds runtime configuration for benchmarks.
 type config struct {
-	filename   string
-	filter     string
-	samples    int
-	duration   time.Duration
-	tableFmt   string
-	showRef    bool
-	dryRun     bool
-	confidence float64
-	threshold  float64
-	bootstrap  int
-	seed       uint64
-	codec      codec
+	filename       string
+	filter         string
+	samples        int
+	duration       time.Duration
+	tableFmt       string
+	showRef        bool
+	dryRun         bool
+	confidence     float64
+	threshold      float64
+	allocThreshold float64
+	bootstrap      int
+	seed           uint64
+	method         Method
+	correction     Correction
+	comparisons    int
+	power          bool
+	fence          Fence
+	trim           bool
+	retries        int
+	paired         bool
+	recovery       Recovery
+	checkpoint     time.Duration
+	codec          Codec
+	customCodec    bool
+	baseline       string
+	saveBaseline   string
+	match          []string
+	glob           []string
+	skip           []string
+	overrides      []override
+	warmup         int
+	tags           []string
+	format         Format
+	output         string
+	pinned         []Option // options of the environment and flags, which scopes cannot override
 }
 
+// Method selects the statistical inference used to compare two sample sets.
+type Method int
+
+const (
+	// MethodBCa uses a bias-corrected and accelerated bootstrap of the median ratio.
+	MethodBCa Method = iota
+
+	// MethodMannWhitney uses a Mann-Whitney U test with a Hodges-Lehmann shift estimate.
+	MethodMannWhitney
+)
+
+// Format selects how the results of a run are reported.
+type Format int
+
+const (
+	// FormatTable prints an aligned table with a row per benchmark.
+	FormatTable Format = iota
+
+	// FormatJSON prints a JSON object per line for each benchmark, followed by
+	// the suite summary and the power analysis when enabled.
+	FormatJSON
+)
+
+// Recovery selects what happens when the results file cannot be decoded.
+type Recovery int
+
+const (
+	// RecoverFail reports an error and does not run any benchmark, so that a
+	// corrupt baseline is never silently replaced or compared against.
+	RecoverFail Recovery = iota
+
+	// RecoverWarn prints a warning and continues without previous results. The
+	// corrupt file is replaced once the first benchmark result is saved.
+	RecoverWarn
+
+	// RecoverRename moves the corrupt file aside with a ".corrupt" suffix, prints
+	// a warning and continues with a fresh results file.
+	RecoverRename
+)
+
+// Correction selects how confidence levels are adjusted for multiple comparisons.
+type Correction int
+
+const (
+	// CorrectionNone uses the configured confidence level for every comparison.
+	CorrectionNone Correction = iota
+
+	// CorrectionBonferroni divides the error rate by the number of comparisons
+	// so the family-wise error rate of the suite stays at the configured level.
+	CorrectionBonferroni
+)
+
 func (c *config) normalize() {
 	if c.filename == "" {
 		c.filename = defaultFilename
@@ -49,15 +122,14 @@ func (c *config) normalize() {
 	if !isFinite(c.threshold) || c.threshold < 0 {
 		c.threshold = 0
 	}
+	if !isFinite(c.allocThreshold) || c.allocThreshold < 0 {
+		c.allocThreshold = 0
+	}
 	if c.bootstrap <= 0 {
 		c.bootstrap = defaultBootstrap
 	}
 	if c.codec == nil {
-		if strings.HasSuffix(c.filename, ".gob") {
-			c.codec = gobCodec{}
-		} else {
-			c.codec = jsonCodec{}
-		}
+		c.codec = codecFor(c.filename)
 	}
 }
 
@@ -65,14 +137,21 @@ func (c *config) normalize() {
 func WithFile(filename string) Option {
 	return func(c *config) {
 		c.filename = filename
-		if strings.HasSuffix(filename, ".gob") {
-			c.codec = gobCodec{}
-		} else {
-			c.codec = jsonCodec{}
+		if !c.customCodec {
+			c.codec = codecFor(filename)
 		}
 	}
 }
 
+// WithCodec sets a custom codec used to load and save benchmark results,
+// regardless of the extension of the results file.
+func WithCodec(codec Codec) Option {
+	return func(c *config) {
+		c.codec = codec
+		c.customCodec = codec != nil
+	}
+}
+
 // WithFilter sets a prefix filter for benchmark names
 func WithFilter(prefix string) Option {
 	return func(c *config) {
@@ -80,6 +159,31 @@ func WithFilter(prefix string) Option {
 	}
 }
 
+// WithMatch runs only the benchmarks matching any of the regular expressions.
+// As with "go test -bench", patterns and names are split on slashes and each
+// element of the pattern must match the corresponding element of the name.
+func WithMatch(patterns ...string) Option {
+	return func(c *config) {
+		c.match = append(c.match, patterns...)
+	}
+}
+
+// WithGlob runs only the benchmarks matching any of the glob patterns, where
+// "*" matches within a single slash-separated element.
+func WithGlob(patterns ...string) Option {
+	return func(c *config) {
+		c.glob = append(c.glob, patterns...)
+	}
+}
+
+// WithSkip skips the benchmarks matching any of the regular expressions, which
+// are split on slashes as with "go test -skip".
+func WithSkip(patterns ...string) Option {
+	return func(c *config) {
+		c.skip = append(c.skip, patterns...)
+	}
+}
+
 // WithSamples sets the number of samples to collect per benchmark
 func WithSamples(n int) Option {
 	return func(c *config) {
@@ -100,6 +204,24 @@ func WithDuration(d time.Duration) Option {
 	}
 }
 
+// WithWarmup sets the number of samples collected and discarded before
+// measuring, e.g. to fill caches or let the runtime settle.
+func WithWarmup(n int) Option {
+	return func(c *config) {
+		if n < 0 {
+			n = 0
+		}
+		c.warmup = n
+	}
+}
+
+// WithTags adds tags stored with the results, e.g. to group benchmarks.
+func WithTags(tags ...string) Option {
+	return func(c *config) {
+		c.tags = append(slices.Clip(c.tags), tags...)
+	}
+}
+
 // WithReference enables reference comparison column
 func WithReference() Option {
 	return func(c *config) {
@@ -114,6 +236,21 @@ func WithDryRun() Option {
 	}
 }
 
+// WithFormat sets how the results of a run are reported.
+func WithFormat(format Format) Option {
+	return func(c *config) {
+		c.format = format
+	}
+}
+
+// WithOutput writes the report to the given file instead of the standard
+// output. Errors and warnings are still printed to the standard output.
+func WithOutput(path string) Option {
+	return func(c *config) {
+		c.output = path
+	}
+}
+
 // WithConfidence sets the confidence level for statistical significance tests
 func WithConfidence(level float64) Option {
 	return func(c *config) {
@@ -135,6 +272,18 @@ func WithThreshold(percent float64) Option {
 	}
 }
 
+// WithAllocThreshold sets the minimum practical change, in percent, required
+// before allocation and bytes/op intervals are reported as an improvement or
+// regression.
+func WithAllocThreshold(percent float64) Option {
+	return func(c *config) {
+		if !isFinite(percent) || percent < 0 {
+			percent = 0
+		}
+		c.allocThreshold = percent
+	}
+}
+
 // WithBootstrap sets the number of bootstrap resamples used for comparisons.
 func WithBootstrap(n int) Option {
 	return func(c *config) {
@@ -152,31 +301,104 @@ func WithSeed(seed uint64) Option {
 	}
 }
 
-// initFlags parses command-line flags and applies them to the config. It
-// recognizes "-bench" to filter benchmarks by prefix and "-n" for dry runs.
-func initFlags(c *config) {
-	fs := flag.NewFlagSet("bench", flag.ContinueOnError)
-	prefix := fs.String("bench", "", "Run only benchmarks with this prefix")
-	dry := fs.Bool("n", false, "dry run - do not update bench.gob")
-
-	// Parse only the flags we care about from os.Args
-	args := []string{}
-	for i := 1; i < len(os.Args); i++ {
-		a := os.Args[i]
-		if strings.HasPrefix(a, "-bench") || a == "-bench" || strings.HasPrefix(a, "-n") || a == "-n" {
-			args = append(args, a)
-			if !strings.Contains(a, "=") && i+1 < len(os.Args) {
-				i++
-				args = append(args, os.Args[i])
-			}
+// WithMethod sets the statistical inference method used for comparisons.
+func WithMethod(method Method) Option {
+	return func(c *config) {
+		c.method = method
+	}
+}
+
+// WithCorrection enables suite-level error control across multiple comparisons.
+func WithCorrection(correction Correction) Option {
+	return func(c *config) {
+		c.correction = correction
+	}
+}
+
+// WithComparisons sets the number of comparisons the correction accounts for.
+// When unset, it is estimated from the matching benchmarks in the results file.
+func WithComparisons(n int) Option {
+	return func(c *config) {
+		if n < 0 {
+			n = 0
 		}
+		c.comparisons = n
 	}
-	_ = fs.Parse(args)
+}
 
-	if *prefix != "" {
-		c.filter = *prefix
+// WithPower prints a power analysis of the stored benchmarks after the run,
+// estimating the minimum detectable change and the samples needed to detect
+// the threshold.
+func WithPower() Option {
+	return func(c *config) {
+		c.power = true
 	}
-	if *dry {
-		c.dryRun = true
+}
+
+// WithFence sets the rule used to detect outliers in timing samples.
+func WithFence(fence Fence) Option {
+	return func(c *config) {
+		c.fence = fence
+	}
+}
+
+// WithTrim excludes outliers detected by the fence before comparing samples.
+func WithTrim() Option {
+	return func(c *config) {
+		c.trim = true
+	}
+}
+
+// WithRetries sets how many times a benchmark is re-run when its samples show
+// a trend over the course of the run, e.g. due to thermal throttling.
+func WithRetries(n int) Option {
+	return func(c *config) {
+		if n < 0 {
+			n = 0
+		}
+		c.retries = n
+	}
+}
+
+// WithPaired compares against the reference using a paired bootstrap, which
+// resamples back-to-back measurements together so that shared noise cancels.
+// It only applies to MethodBCa and is ignored by MethodMannWhitney.
+func WithPaired() Option {
+	return func(c *config) {
+		c.paired = true
+	}
+}
+
+// WithRecovery sets the policy applied when the results file cannot be decoded.
+func WithRecovery(policy Recovery) Option {
+	return func(c *config) {
+		c.recovery = policy
+	}
+}
+
+// WithCheckpoint sets how often results are written to disk while the suite
+// runs. Results are always written at the end; zero writes after every benchmark.
+func WithCheckpoint(interval time.Duration) Option {
+	return func(c *config) {
+		if interval < 0 {
+			interval = 0
+		}
+		c.checkpoint = interval
+	}
+}
+
+// WithBaseline compares results against the named baseline stored in the results
+// file instead of the latest run.
+func WithBaseline(name string) Option {
+	return func(c *config) {
+		c.baseline = name
+	}
+}
+
+// WithSaveBaseline saves results into the named baseline of the results file
+// instead of the latest run, e.g. to record a "main" baseline.
+func WithSaveBaseline(name string) Option {
+	return func(c *config) {
+		c.saveBaseline = name
 	}
 }
@@ -4,6 +4,7 @@
 package bench
 
 import (
+	"io"
 	"math"
 	"os"
 	"testing"
@@ -22,8 +23,25 @@ func TestWithOptions(t *testing.T) {
 	WithDryRun()(&cfg)
 	WithConfidence(95.5)(&cfg)
 	WithThreshold(7.5)(&cfg)
+	WithAllocThreshold(2.5)(&cfg)
 	WithBootstrap(1234)(&cfg)
 	WithSeed(99)(&cfg)
+	WithMethod(MethodMannWhitney)(&cfg)
+	WithCorrection(CorrectionBonferroni)(&cfg)
+	WithComparisons(10)(&cfg)
+	WithFence(FenceMAD)(&cfg)
+	WithTrim()(&cfg)
+	WithRetries(3)(&cfg)
+	WithPaired()(&cfg)
+	WithRecovery(RecoverRename)(&cfg)
+	WithCheckpoint(time.Second)(&cfg)
+	WithBaseline("main")(&cfg)
+	WithSaveBaseline("v1.4")(&cfg)
+	WithMatch("a", "b")(&cfg)
+	WithGlob("c/*")(&cfg)
+	WithSkip("d")(&cfg)
+	WithWarmup(2)(&cfg)
+	WithTags("x", "y")(&cfg)
 
 	assert.Equal(t, "foo.json", cfg.filename)
 	assert.Equal(t, "bar", cfg.filter)
@@ -35,8 +53,25 @@ func TestWithOptions(t *testing.T) {
 	assert.True(t, ok)
 	assert.InDelta(t, 95.5, cfg.confidence, 0.001)
 	assert.InDelta(t, 7.5, cfg.threshold, 0.001)
+	assert.InDelta(t, 2.5, cfg.allocThreshold, 0.001)
 	assert.Equal(t, 1234, cfg.bootstrap)
 	assert.Equal(t, uint64(99), cfg.seed)
+	assert.Equal(t, MethodMannWhitney, cfg.method)
+	assert.Equal(t, CorrectionBonferroni, cfg.correction)
+	assert.Equal(t, 10, cfg.comparisons)
+	assert.Equal(t, FenceMAD, cfg.fence)
+	assert.True(t, cfg.trim)
+	assert.Equal(t, 3, cfg.retries)
+	assert.True(t, cfg.paired)
+	assert.Equal(t, RecoverRename, cfg.recovery)
+	assert.Equal(t, time.Second, cfg.checkpoint)
+	assert.Equal(t, "main", cfg.baseline)
+	assert.Equal(t, "v1.4", cfg.saveBaseline)
+	assert.Equal(t, []string{"a", "b"}, cfg.match)
+	assert.Equal(t, []string{"c/*"}, cfg.glob)
+	assert.Equal(t, []string{"d"}, cfg.skip)
+	assert.Equal(t, 2, cfg.warmup)
+	assert.Equal(t, []string{"x", "y"}, cfg.tags)
 }
 
 func TestInvalidOptionsAreClamped(t *testing.T) {
@@ -83,25 +118,16 @@ func TestShouldRun(t *testing.T) {
 	assert.False(t, b.shouldRun("bar"))
 	b.filter = ""
 	assert.True(t, b.shouldRun("anything"))
-}
-
-func TestInitFlagsPreservesExistingConfig(t *testing.T) {
-	oldArgs := os.Args
-	t.Cleanup(func() {
-		os.Args = oldArgs
-	})
-	os.Args = []string{"test"}
-
-	cfg := config{dryRun: true, filter: "keep"}
-	initFlags(&cfg)
 
-	assert.True(t, cfg.dryRun)
-	assert.Equal(t, "keep", cfg.filter)
+	// The prefix filter and patterns must both match
+	b = &B{config: config{filter: "foo", skip: []string{"/bar"}}}
+	assert.True(t, b.shouldRun("foo/baz"))
+	assert.False(t, b.shouldRun("foo/bar"))
 }
 
 func TestRunAndFiltering(t *testing.T) {
 	file := "test_bench2.json"
-	defer os.Remove(file)
+	defer removeResults(file)
 	var ran, ranRef bool
 	Run(func(b *B) {
 		b.Run("foo", func(i int) { ran = true })
@@ -113,7 +139,7 @@ func TestRunAndFiltering(t *testing.T) {
 
 func TestRunWithReferenceAndNoPrev(t *testing.T) {
 	file := "test_bench3.json"
-	defer os.Remove(file)
+	defer removeResults(file)
 	Run(func(b *B) {
 		b.Run("bench", func(i int) {}, func(i int) {})
 	}, WithFile(file), WithReference())
@@ -123,7 +149,7 @@ func TestRunWithReferenceAndNoPrev(t *testing.T) {
 
 func TestRunDryRun(t *testing.T) {
 	file := "test_bench_dry.json"
-	defer os.Remove(file)
+	defer removeResults(file)
 	Run(func(b *B) {
 		b.Run("bench", func(i int) {})
 	}, WithFile(file), WithDryRun())
@@ -157,7 +183,7 @@ func TestBCaBootstrap(t *testing.T) {
 
 func TestRunWithBCaBootstrap(t *testing.T) {
 	file := "test_bca_bootstrap.json"
-	defer os.Remove(file)
+	defer removeResults(file)
 
 	// Test that benchmark execution works with BCa bootstrap (always enabled)
 	Run(func(b *B) {
@@ -170,13 +196,15 @@ func TestRunWithBCaBootstrap(t *testing.T) {
 	_, err := os.Stat(file)
 	assert.NoError(t, err, "results file should be created")
 
-	loaded := jsonCodec{}.load(file)
-	assert.Len(t, loaded["test_bca"].Allocs, 10, "allocation samples should be saved with timing samples")
+	loaded, err := jsonCodec{}.Load(file)
+	assert.NoError(t, err)
+	assert.Len(t, loaded[LatestBaseline]["test_bca"].Allocs, 10, "allocation samples should be saved with timing samples")
+	assert.Len(t, loaded[LatestBaseline]["test_bca"].Bytes, 10, "byte samples should be saved with timing samples")
 }
 
 func TestRunNRequiresPositiveOps(t *testing.T) {
 	file := "test_runn_invalid.json"
-	defer os.Remove(file)
+	defer removeResults(file)
 
 	assert.PanicsWithValue(t, "bench: RunN function must return a positive operation count", func() {
 		Run(func(b *B) {
@@ -197,7 +225,7 @@ func TestRunNDetectsOpsOverflow(t *testing.T) {
 
 func TestAssert(t *testing.T) {
 	file := "test_assert.json"
-	defer os.Remove(file)
+	defer removeResults(file)
 
 	// baseline run to create previous results
 	Run(func(b *B) {
@@ -216,3 +244,203 @@ func TestAssert(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, before.ModTime(), after.ModTime(), "file should not be modified")
 }
+
+func TestAssertWithoutDryRun(t *testing.T) {
+	file := "test_assert_saved.json"
+	defer removeResults(file)
+	t.Setenv("BENCH_DRY_RUN", "false")
+
+	// Results pending after the last checkpoint are written when the suite ends
+	Assert(t, func(b *B) {
+		b.Run("a", func(i int) {})
+		b.Run("b", func(i int) {})
+		b.Run("c", func(i int) {})
+	}, WithFile(file), WithSamples(3), WithDuration(time.Microsecond), WithCheckpoint(time.Hour))
+
+	loaded, err := jsonCodec{}.Load(file)
+	assert.NoError(t, err)
+	assert.Len(t, loaded[LatestBaseline], 3)
+	for _, name := range []string{"a", "b", "c"} {
+		assert.Len(t, loaded[LatestBaseline][name].Samples, 3, name)
+	}
+}
+
+func TestAdjustedConfidence(t *testing.T) {
+	file := "test_correction.json"
+	defer removeResults(file)
+
+	b := &B{config: config{filename: file, codec: jsonCodec{}, confidence: 95, bootstrap: 100}}
+	b.saveResult(Result{Name: "foo/a", Samples: []float64{1, 2}})
+	b.saveResult(Result{Name: "foo/b", Samples: []float64{1, 2}})
+	b.saveResult(Result{Name: "bar", Samples: []float64{1, 2}})
+
+	// Without correction the configured level is used as-is
+	b.estimateComparisons()
+	confidence, comparisons := b.adjustedConfidence()
+	assert.Equal(t, 0.95, confidence)
+	assert.Equal(t, 1, comparisons)
+
+	// Bonferroni counts the matching baseline benchmarks
+	b.correction = CorrectionBonferroni
+	b.filter = "foo"
+	b.estimateComparisons()
+	confidence, comparisons = b.adjustedConfidence()
+	assert.InDelta(t, 0.975, confidence, 1e-12)
+	assert.Equal(t, 2, comparisons)
+
+	report := b.compare([]float64{10, 11, 12}, []float64{10, 11, 12})
+	assert.InDelta(t, 0.975, report.Confidence, 1e-12)
+	assert.Equal(t, 2, report.Comparisons)
+
+	// A suite without previous results counts a single comparison
+	b.comparisons = 0
+	b.filter = "new"
+	b.estimateComparisons()
+	assert.Equal(t, 1, b.comparisons)
+}
+
+func TestRunWithCorruptResults(t *testing.T) {
+	file := "test_corrupt.json"
+	defer removeResults(file)
+	os.WriteFile(file, []byte("bad"), 0644)
+
+	// By default nothing runs and the corrupt file is left untouched
+	var ran bool
+	Run(func(b *B) {
+		b.Run("bench", func(i int) { ran = true })
+	}, WithFile(file), WithSamples(2))
+	assert.False(t, ran)
+	data, _ := os.ReadFile(file)
+	assert.Equal(t, "bad", string(data))
+
+	// Warning continues without previous results and replaces the file
+	Run(func(b *B) {
+		b.Run("bench", func(i int) { ran = true })
+	}, WithFile(file), WithSamples(2), WithRecovery(RecoverWarn))
+	assert.True(t, ran)
+	loaded, err := jsonCodec{}.Load(file)
+	assert.NoError(t, err)
+	assert.Contains(t, loaded[LatestBaseline], "bench")
+}
+
+func TestRunRenamesCorruptResults(t *testing.T) {
+	file := "test_corrupt_rename.json"
+	defer removeResults(file)
+	defer os.Remove(file + corruptSuffix)
+	os.WriteFile(file, []byte("bad"), 0644)
+
+	Run(func(b *B) {
+		b.Run("bench", func(i int) {})
+	}, WithFile(file), WithSamples(2), WithRecovery(RecoverRename))
+
+	data, err := os.ReadFile(file + corruptSuffix)
+	assert.NoError(t, err)
+	assert.Equal(t, "bad", string(data))
+
+	loaded, err := jsonCodec{}.Load(file)
+	assert.NoError(t, err)
+	assert.Contains(t, loaded[LatestBaseline], "bench")
+}
+
+func TestAssertFailsOnCorruptResults(t *testing.T) {
+	file := "test_corrupt_assert.json"
+	defer removeResults(file)
+	os.WriteFile(file, []byte("bad"), 0644)
+
+	mock := &testing.T{}
+	Assert(mock, func(b *B) {
+		b.Run("bench", func(i int) {})
+	}, WithFile(file), WithSamples(2))
+	assert.True(t, mock.Failed())
+}
+
+func TestRunWithScope(t *testing.T) {
+	file := "test_bench_scope.json"
+	defer removeResults(file)
+
+	// Each call outlasts the sample duration, so every sample makes a single call
+	calls := 0
+	slow := func(i int) int {
+		calls++
+		time.Sleep(50 * time.Microsecond)
+		return 1
+	}
+
+	Run(func(b *B) {
+		b.With(WithSamples(3), WithWarmup(2), WithTags("slow"), WithThreshold(50)).RunN("slow", slow)
+		assert.Equal(t, 5, calls)
+		assert.Equal(t, 4, b.samples)
+		assert.Equal(t, defaultThreshold, b.threshold)
+		assert.Equal(t, []string{"suite"}, b.tags)
+
+		b.With(WithTags("fast")).Run("fast", func(i int) {}, func(i int) {})
+		b.Run("plain", func(i int) {})
+	}, WithFile(file), WithSamples(4), WithDuration(time.Microsecond), WithTags("suite"))
+
+	loaded, err := jsonCodec{}.Load(file)
+	assert.NoError(t, err)
+	assert.Len(t, loaded[LatestBaseline]["slow"].Samples, 3)
+	assert.Equal(t, []string{"suite", "slow"}, loaded[LatestBaseline]["slow"].Tags)
+	assert.Len(t, loaded[LatestBaseline]["fast"].Samples, 4)
+	assert.Equal(t, []string{"suite", "fast"}, loaded[LatestBaseline]["fast"].Tags)
+	assert.Equal(t, []string{"suite"}, loaded[LatestBaseline]["plain"].Tags)
+}
+
+func TestScopePrecedence(t *testing.T) {
+	cfg := defaultConfig()
+	WithFile("suite.json")(&cfg)
+	WithTags("suite")(&cfg)
+	assert.NoError(t, loadEnv(&cfg, envOf(map[string]string{"BENCH_CONFIDENCE": "95"})))
+	_, err := initFlags(&cfg, []string{"-bench.duration", "2ms"}, io.Discard)
+	assert.NoError(t, err)
+
+	b := &B{config: cfg}
+	restore := Scope{b: b, opts: []Option{
+		WithConfidence(90), WithDuration(time.Second), WithSamples(7), WithThreshold(20), WithWarmup(1), WithTags("scoped"),
+		WithFile("scoped.json"), WithCodec(csvCodec{}), WithDryRun(), WithSaveBaseline("scoped"),
+	}}.apply()
+
+	// The environment and flags take precedence over the scope
+	assert.Equal(t, 95.0, b.confidence)
+	assert.Equal(t, 2*time.Millisecond, b.duration)
+	assert.Equal(t, 7, b.samples)
+	assert.Equal(t, 20.0, b.threshold)
+	assert.Equal(t, 1, b.warmup)
+	assert.Equal(t, []string{"suite", "scoped"}, b.tags)
+
+	// Settings outside of the scoped ones are left untouched
+	assert.Equal(t, "suite.json", b.filename)
+	assert.Equal(t, jsonCodec{}, b.codec)
+	assert.False(t, b.dryRun)
+	assert.Empty(t, b.saveBaseline)
+
+	restore()
+	assert.Equal(t, defaultSamples, b.samples)
+	assert.Equal(t, []string{"suite"}, b.tags)
+}
+
+// recorder records the failures and logs of a test.
+type recorder struct {
+	testing.TB
+	errors, logs int
+}
+
+func (r *recorder) Errorf(string, ...any) { r.errors++ }
+func (r *recorder) Logf(string, ...any)   { r.logs++ }
+
+func TestCheckRegression(t *testing.T) {
+	rec := &recorder{}
+	b := &B{t: rec}
+
+	b.checkRegression("a", Report{Significant: true, Delta: -0.5}, "")
+	b.checkRegression("b", Report{Significant: false, Delta: 0.5}, "")
+	assert.Equal(t, 0, rec.errors+rec.logs)
+
+	// Regressions of drifting samples are logged rather than failed
+	b.checkRegression("c", Report{Significant: true, Delta: 0.5, Unstable: true}, "")
+	assert.Equal(t, 0, rec.errors)
+	assert.Equal(t, 1, rec.logs)
+
+	b.checkRegression("d", Report{Significant: true, Delta: 0.5}, "")
+	assert.Equal(t, 1, rec.errors)
+}
@@ -0,0 +1,150 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root
+
+// Command bench manages benchmark results files.
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/kelindar/bench"
+)
+
+const usage = `Usage: bench [-file bench.gob] <command> [arguments]
+
+Commands:
+  baseline list                 list the baselines stored in the results file
+  baseline copy <from> <to>     snapshot a baseline under another name
+  baseline delete <name>        delete a baseline and its results
+  merge [-policy p] <files...>  merge results files, where p is newest, history or fail
+  prune [-bench re] [-glob p] [-skip p] [-age d]
+                                remove results of the selected benchmarks older than a duration
+  rename <from> <to>            rename a benchmark in every baseline
+
+Benchmarks are selected as in a run: -bench takes a regular expression and
+-glob a glob pattern, each matched against the leading "/"-separated elements
+of the name, and -skip excludes names matching a glob pattern.
+
+The default baseline, holding the latest run, is named "latest".
+`
+
+var errUsage = errors.New("invalid command")
+
+var policies = map[string]bench.MergePolicy{
+	"newest":  bench.MergeNewest,
+	"history": bench.MergeHistory,
+	"fail":    bench.MergeFail,
+}
+
+func main() {
+	fs := flag.NewFlagSet("bench", flag.ExitOnError)
+	file := fs.String("file", "bench.gob", "results file")
+	fs.Usage = func() {
+		fmt.Fprint(fs.Output(), usage)
+		fs.PrintDefaults()
+	}
+	fs.Parse(os.Args[1:])
+
+	if err := run(os.Stdout, *file, fs.Args()); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return
+		}
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		if err == errUsage {
+			fs.Usage()
+		}
+		os.Exit(1)
+	}
+}
+
+// run executes a single command against the results file, writing its output
+// to w.
+func run(w io.Writer, file string, args []string) error {
+	if len(args) == 0 {
+		return errUsage
+	}
+
+	switch cmd, args := args[0], args[1:]; cmd {
+	case "baseline":
+		return runBaseline(w, file, args)
+	case "merge":
+		fs := flag.NewFlagSet("merge", flag.ContinueOnError)
+		policy := fs.String("policy", "newest", "conflict policy: newest, history or fail")
+		if err := fs.Parse(args); err != nil {
+			return err
+		}
+
+		p, ok := policies[*policy]
+		if !ok || fs.NArg() == 0 {
+			return errUsage
+		}
+		return bench.Merge(fs.Args(), p, bench.WithFile(file))
+	case "prune":
+		fs := flag.NewFlagSet("prune", flag.ContinueOnError)
+		match := fs.String("bench", "", "regular expression of benchmark names to remove")
+		glob := fs.String("glob", "", "glob pattern of benchmark names to remove")
+		skip := fs.String("skip", "", "glob pattern of benchmark names to keep")
+		age := fs.Duration("age", 0, "remove only results older than this")
+		if err := fs.Parse(args); err != nil {
+			return err
+		}
+
+		if fs.NArg() != 0 {
+			return errUsage
+		}
+
+		opts := []bench.Option{bench.WithFile(file)}
+		if *match != "" {
+			opts = append(opts, bench.WithMatch(*match))
+		}
+		if *glob != "" {
+			opts = append(opts, bench.WithGlob(*glob))
+		}
+		if *skip != "" {
+			opts = append(opts, bench.WithSkip(*skip))
+		}
+
+		removed, err := bench.Prune(*age, opts...)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(w, "removed %d results\n", removed)
+		return nil
+	case "rename":
+		if len(args) != 2 {
+			return errUsage
+		}
+		return bench.Rename(args[0], args[1], bench.WithFile(file))
+	default:
+		return errUsage
+	}
+}
+
+// runBaseline executes a baseline command against the results file.
+func runBaseline(w io.Writer, file string, args []string) error {
+	if len(args) == 0 {
+		return errUsage
+	}
+
+	switch cmd, args := args[0], args[1:]; {
+	case cmd == "list" && len(args) == 0:
+		names, err := bench.Baselines(bench.WithFile(file))
+		if err != nil {
+			return err
+		}
+		for _, name := range names {
+			fmt.Fprintln(w, name)
+		}
+		return nil
+	case cmd == "copy" && len(args) == 2:
+		return bench.CopyBaseline(args[0], args[1], bench.WithFile(file))
+	case cmd == "delete" && len(args) == 1:
+		return bench.DeleteBaseline(args[0], bench.WithFile(file))
+	default:
+		return errUsage
+	}
+}
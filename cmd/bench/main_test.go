@@ -0,0 +1,87 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root
+
+package main
+
+import (
+	"bytes"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/kelindar/bench"
+	"github.com/stretchr/testify/assert"
+)
+
+// seed runs the named benchmarks and stores them in a results file of the temporary
+// working directory.
+func seed(t *testing.T, file string, names ...string) {
+	t.Helper()
+	bench.Run(func(b *bench.B) {
+		for _, name := range names {
+			b.Run(name, func(i int) {})
+		}
+	}, bench.WithFile(file), bench.WithSamples(2), bench.WithDuration(time.Millisecond))
+}
+
+func TestBaseline(t *testing.T) {
+	t.Chdir(t.TempDir())
+	seed(t, "bench.json", "a")
+
+	assert.NoError(t, run(io.Discard, "bench.json", []string{"baseline", "copy", "latest", "v1"}))
+
+	var out bytes.Buffer
+	assert.NoError(t, run(&out, "bench.json", []string{"baseline", "list"}))
+	assert.Equal(t, "latest\nv1\n", out.String())
+
+	assert.NoError(t, run(io.Discard, "bench.json", []string{"baseline", "delete", "v1"}))
+	names, err := bench.Baselines(bench.WithFile("bench.json"))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"latest"}, names)
+}
+
+func TestMergeAndRename(t *testing.T) {
+	t.Chdir(t.TempDir())
+	seed(t, "shard1.json", "a")
+	seed(t, "shard2.json", "b")
+
+	assert.NoError(t, run(io.Discard, "bench.json", []string{"merge", "-policy", "fail", "shard1.json", "shard2.json"}))
+	assert.NoError(t, run(io.Discard, "bench.json", []string{"rename", "a", "c"}))
+	assert.Error(t, run(io.Discard, "bench.json", []string{"rename", "a", "c"}))
+
+	results, err := bench.Analyze(bench.WithFile("bench.json"))
+	assert.NoError(t, err)
+	assert.Len(t, results, 2)
+}
+
+func TestPrune(t *testing.T) {
+	t.Chdir(t.TempDir())
+	seed(t, "bench.json", "legacy/a/b", "legacy/keep", "current")
+
+	var out bytes.Buffer
+	assert.NoError(t, run(&out, "bench.json", []string{"prune", "-glob", "legacy/*", "-skip", "legacy/keep"}))
+	assert.Equal(t, "removed 1 results\n", out.String())
+
+	out.Reset()
+	assert.NoError(t, run(&out, "bench.json", []string{"prune", "-bench", "^cur"}))
+	assert.Equal(t, "removed 1 results\n", out.String())
+
+	assert.Error(t, run(io.Discard, "bench.json", []string{"prune"}))
+	assert.Error(t, run(io.Discard, "bench.json", []string{"prune", "-age", "nope"}))
+}
+
+func TestUsage(t *testing.T) {
+	t.Chdir(t.TempDir())
+	for _, args := range [][]string{
+		nil,
+		{"unknown"},
+		{"baseline"},
+		{"baseline", "copy", "latest"},
+		{"merge"},
+		{"merge", "-policy", "oldest", "shard.json"},
+		{"prune", "extra"},
+		{"rename", "a"},
+	} {
+		assert.ErrorIs(t, run(io.Discard, "bench.json", args), errUsage, args)
+	}
+}
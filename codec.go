@@ -4,85 +4,368 @@
 package bench
 
 import (
-	"encoding/gob"
-	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"io/fs"
 	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
 )
 
-// codec defines methods for encoding and decoding benchmark results.
-type codec interface {
-	load(filename string) map[string]Result
-	save(filename string, results map[string]Result) error
+const (
+	// backupSuffix is appended to the results filename to keep the previous version
+	backupSuffix = ".bak"
+
+	// corruptSuffix is appended to results files moved aside by RecoverRename
+	corruptSuffix = ".corrupt"
+)
+
+// Results holds the contents of a results file, keyed by baseline and then by
+// benchmark name. The results of the latest run are kept in LatestBaseline.
+type Results map[string]map[string]Result
+
+// Codec defines methods for encoding and decoding benchmark results. A missing
+// file must load as an empty set of results without an error, while a file that
+// cannot be decoded must return an error.
+type Codec interface {
+	Load(filename string) (Results, error)
+	Save(filename string, results Results) error
+}
+
+// Appender is optionally implemented by codecs which can add results of a
+// baseline to the end of the file without rewriting the existing ones.
+type Appender interface {
+	Append(filename, baseline string, results []Result) error
 }
 
-type jsonCodec struct{}
+// Locker is optionally implemented by codecs whose storage must be locked across
+// processes while results are merged into it.
+type Locker interface {
+	Lock(filename string) (unlock func(), err error)
+}
 
-type gobCodec struct{}
+// Mover is optionally implemented by codecs which can move unreadable results
+// aside for RecoverRename, returning where they were moved.
+type Mover interface {
+	MoveAside(filename string) (string, error)
+}
 
-func (jsonCodec) load(filename string) map[string]Result {
-	data, err := os.ReadFile(filename)
-	if err != nil {
-		return make(map[string]Result)
+// fileStore implements locking and recovery for the codecs backed by files.
+type fileStore struct{}
+
+// Lock takes an advisory lock on a sibling ".lock" file.
+func (fileStore) Lock(filename string) (func(), error) {
+	return lockResults(filename)
+}
+
+// MoveAside renames the file and its backup with a ".corrupt" suffix.
+func (fileStore) MoveAside(filename string) (string, error) {
+	for _, name := range []string{filename, filename + backupSuffix} {
+		if err := os.Rename(name, name+corruptSuffix); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return "", err
+		}
 	}
-	var results map[string]Result
-	if err := json.Unmarshal(data, &results); err != nil {
-		return make(map[string]Result)
+	return filename + corruptSuffix, nil
+}
+
+// jsonCodec stores results as indented JSON, optionally gzip-compressed.
+type jsonCodec struct {
+	fileStore
+	compress bool
+}
+
+// gobCodec stores results in gob format with packed samples, optionally
+// gzip-compressed.
+type gobCodec struct {
+	fileStore
+	compress bool
+}
+
+var codecs = struct {
+	sync.RWMutex
+	byExt map[string]Codec
+}{
+	byExt: map[string]Codec{
+		".json":    jsonCodec{},
+		".gob":     gobCodec{},
+		".journal": journalCodec{},
+		".csv":     csvCodec{},
+		".json.gz": jsonCodec{compress: true},
+		".gob.gz":  gobCodec{compress: true},
+	},
+}
+
+// RegisterCodec registers a codec for result files whose name ends with the
+// given extension (e.g. ".db"), replacing any codec previously registered for it.
+func RegisterCodec(ext string, codec Codec) {
+	codecs.Lock()
+	defer codecs.Unlock()
+	if codec == nil {
+		delete(codecs.byExt, ext)
+		return
 	}
-	return results
+	codecs.byExt[ext] = codec
 }
 
-func (jsonCodec) save(filename string, results map[string]Result) error {
-	data, err := json.MarshalIndent(results, "", "  ")
-	if err != nil {
-		return err
+// lookupCodec returns the codec registered for the extension.
+func lookupCodec(ext string) (Codec, bool) {
+	codecs.RLock()
+	defer codecs.RUnlock()
+	codec, ok := codecs.byExt[ext]
+	return codec, ok
+}
+
+// codecFor selects the codec registered for the longest extension matching the
+// filename, falling back to JSON.
+func codecFor(filename string) Codec {
+	codecs.RLock()
+	defer codecs.RUnlock()
+
+	var found Codec = jsonCodec{}
+	longest := 0
+	for ext, codec := range codecs.byExt {
+		if len(ext) > longest && strings.HasSuffix(filename, ext) {
+			found, longest = codec, len(ext)
+		}
 	}
-	return os.WriteFile(filename, data, 0644)
+	return found
+}
+
+func (c jsonCodec) Load(filename string) (Results, error) {
+	return readWithBackup(filename, decompress(c.compress, decodeJSON))
+}
+
+func (c jsonCodec) Save(filename string, results Results) error {
+	return writeAtomic(filename, compress(c.compress, func(w io.Writer) error {
+		return encodeJSON(w, results)
+	}))
 }
 
-func (gobCodec) load(filename string) map[string]Result {
+func (c gobCodec) Load(filename string) (Results, error) {
+	return readWithBackup(filename, decompress(c.compress, decodeGob))
+}
+
+func (c gobCodec) Save(filename string, results Results) error {
+	return writeAtomic(filename, compress(c.compress, func(w io.Writer) error {
+		return encodeGob(w, results)
+	}))
+}
+
+// readWithBackup decodes the results file, recovering from the backup of the
+// previous version when the file is missing or cannot be decoded. It fails
+// only when neither can be decoded and at least one of them exists, or when the
+// file was written by a newer release.
+func readWithBackup(filename string, decode func(io.Reader) (Results, error)) (Results, error) {
+	results, err := readFile(filename, decode)
+	switch {
+	case err == nil:
+		return results, nil
+	case errors.Is(err, ErrUnsupportedVersion):
+		return nil, err // Never fall back to an older version of a newer file
+	}
+
+	backup, backupErr := readFile(filename+backupSuffix, decode)
+	switch {
+	case backupErr == nil:
+		return backup, nil
+	case errors.Is(err, fs.ErrNotExist) && errors.Is(backupErr, fs.ErrNotExist):
+		return make(Results), nil
+	case errors.Is(err, fs.ErrNotExist):
+		return nil, backupErr
+	default:
+		return nil, err
+	}
+}
+
+// readFile opens and decodes a single results file.
+func readFile(filename string, decode func(io.Reader) (Results, error)) (Results, error) {
 	f, err := os.Open(filename)
 	if err != nil {
-		return make(map[string]Result)
+		return nil, err
 	}
 	defer f.Close()
-	dec := gob.NewDecoder(f)
-	var results map[string]Result
-	if err := dec.Decode(&results); err != nil {
-		return make(map[string]Result)
+
+	results, err := decode(f)
+	if err != nil {
+		return nil, fmt.Errorf("bench: unable to decode %s: %w", filename, err)
+	}
+	if results == nil {
+		results = make(Results)
 	}
-	return results
+	return results, nil
 }
 
-func (gobCodec) save(filename string, results map[string]Result) error {
-	f, err := os.Create(filename)
+// writeAtomic writes the results file through a temporary file which is synced
+// and renamed over the original, so a crash never leaves a partially written
+// file behind. The previous version is kept as a backup.
+func writeAtomic(filename string, write func(io.Writer) error) error {
+	tmp, err := os.CreateTemp(filepath.Dir(filename), filepath.Base(filename)+".tmp*")
 	if err != nil {
 		return err
 	}
-	defer f.Close()
-	enc := gob.NewEncoder(f)
-	return enc.Encode(results)
+	defer os.Remove(tmp.Name())
+
+	if err := write(tmp); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		return err
+	}
+
+	// Keep the previous version, which is also used if we crash before the rename
+	if _, err := os.Stat(filename); err == nil {
+		if err := os.Rename(filename, filename+backupSuffix); err != nil {
+			return err
+		}
+	}
+	return os.Rename(tmp.Name(), filename)
 }
 
 // loadResults loads previous results using the configured codec.
-func (r *B) loadResults() map[string]Result {
+func (r *B) loadResults() (Results, error) {
 	if r.codec == nil {
 		r.codec = jsonCodec{}
 	}
-	return r.codec.load(r.filename)
+	return r.codec.Load(r.filename)
+}
+
+// open validates the configuration, loads the results file once for the whole
+// suite and applies the recovery policy when it cannot be decoded. It returns an
+// error only when the run must not proceed.
+func (r *B) open() error {
+	m, err := newMatcher(&r.config)
+	if err != nil {
+		return err
+	}
+	r.matcher = m
+
+	results, err := r.loadResults()
+	if err == nil {
+		r.results = results
+		return nil
+	}
+
+	switch r.recovery {
+	case RecoverWarn:
+		fmt.Printf("Warning: %v, continuing without previous results\n", err)
+	case RecoverRename:
+		mover, ok := r.codec.(Mover)
+		if !ok {
+			return fmt.Errorf("%w, and the codec cannot move it aside", err)
+		}
+
+		moved, moveErr := mover.MoveAside(r.filename)
+		if moveErr != nil {
+			return fmt.Errorf("bench: unable to move corrupt results aside: %w", moveErr)
+		}
+
+		fmt.Printf("Warning: %v, moved to %s\n", err, moved)
+	default:
+		return err
+	}
+
+	r.results = make(Results)
+	return nil
+}
+
+// previous returns the results known at the start of the suite, including the
+// results saved since, loading them on first use.
+func (r *B) previous() Results {
+	if r.results == nil {
+		if results, err := r.loadResults(); err == nil {
+			r.results = results
+		} else {
+			r.results = make(Results)
+		}
+	}
+	return r.results
 }
 
-// saveResult saves a single result incrementally using the configured codec.
+// saveResult records a single result in the saved baseline and writes it to
+// disk at the next checkpoint, so that an interrupted suite loses at most one
+// checkpoint interval of results.
 func (r *B) saveResult(result Result) {
 	if r.dryRun {
 		return
 	}
+
+	r.previous().put(r.saveBaseline, result)
+	if r.pending == nil {
+		r.pending = make(map[string]Result)
+	}
+
+	r.pending[result.Name] = result
+	if time.Since(r.flushed) >= r.checkpoint {
+		r.flush()
+	}
+}
+
+// flush writes the pending results using the configured codec. The
+// read-modify-write cycle runs under a cross-process lock and merges into the
+// latest version of the file, so that concurrent runs do not lose each other's
+// results.
+func (r *B) flush() {
+	if len(r.pending) == 0 {
+		return
+	}
 	if r.codec == nil {
 		r.codec = jsonCodec{}
 	}
-	current := r.loadResults()
-	current[result.Name] = result
-	if err := r.codec.save(r.filename, current); err != nil {
+
+	unlock, err := lockCodec(r.codec, r.filename)
+	if err != nil {
+		fmt.Printf("Error locking results file: %v\n", err)
+		return
+	}
+	defer unlock()
+
+	// Append-only codecs add the results without reading the file back
+	if journal, ok := r.codec.(Appender); ok {
+		results := make([]Result, 0, len(r.pending))
+		for _, result := range r.pending {
+			results = append(results, result)
+		}
+		if err := journal.Append(r.filename, r.saveBaseline, results); err != nil {
+			fmt.Printf("Error writing results file: %v\n", err)
+			return
+		}
+
+		clear(r.pending)
+		r.flushed = time.Now()
+		return
+	}
+
+	// Never overwrite a file we could not read, unless asked to continue
+	current, err := r.loadResults()
+	switch {
+	case err != nil && r.recovery != RecoverWarn:
+		fmt.Printf("Error reading results file: %v\n", err)
+		return
+	case err != nil:
+		current = make(Results)
+	}
+
+	for _, result := range r.pending {
+		current.put(r.saveBaseline, result)
+	}
+
+	// Keep pending results for the next checkpoint if writing fails
+	if err := r.codec.Save(r.filename, current); err != nil {
 		fmt.Printf("Error writing results file: %v\n", err)
+		return
 	}
+
+	clear(r.pending)
+	r.flushed = time.Now()
 }
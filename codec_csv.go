@@ -0,0 +1,216 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root
+
+package bench
+
+import (
+	"bufio"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// tagSeparator separates the tags of a result within a single column.
+const tagSeparator = ";"
+
+// csvHeader lists the columns written for every sample.
+var csvHeader = []string{"name", "timestamp", "sample", "ns_op", "allocs_op", "bytes_op", "outliers", "unstable", "tags", "baseline"}
+
+// csvCodec stores results as CSV with one row per sample, for analysis in
+// notebooks and spreadsheets.
+type csvCodec struct {
+	fileStore
+}
+
+func (csvCodec) Load(filename string) (Results, error) {
+	return readWithBackup(filename, ReadCSV)
+}
+
+func (csvCodec) Save(filename string, results Results) error {
+	return writeAtomic(filename, func(w io.Writer) error {
+		return WriteCSV(w, results)
+	})
+}
+
+// WriteCSV writes the results as CSV with one row per sample, including the
+// name, run timestamp, sample index, ns/op, allocs/op and bytes/op along with
+// the outlier count, stability, tags and baseline of the run. Results without
+// samples are written as a single row with empty sample columns.
+func WriteCSV(w io.Writer, results Results) error {
+	out := csv.NewWriter(w)
+	if err := out.Write(csvHeader); err != nil {
+		return err
+	}
+
+	for _, baseline := range slices.Sorted(maps.Keys(results)) {
+		if err := writeCSVBaseline(out, baseline, results[baseline]); err != nil {
+			return err
+		}
+	}
+
+	out.Flush()
+	return out.Error()
+}
+
+// writeCSVBaseline writes the rows of the results of a baseline, sorted by name.
+func writeCSVBaseline(out *csv.Writer, baseline string, results map[string]Result) error {
+	for _, name := range slices.Sorted(maps.Keys(results)) {
+		result := results[name]
+
+		// A result without samples keeps a single row with empty sample columns
+		for i := range max(1, len(result.Samples)) {
+			index := ""
+			if i < len(result.Samples) {
+				index = strconv.Itoa(i)
+			}
+
+			if err := out.Write([]string{
+				name,
+				strconv.FormatInt(result.Timestamp, 10),
+				index,
+				formatSampleAt(result.Samples, i),
+				formatSampleAt(result.Allocs, i),
+				formatSampleAt(result.Bytes, i),
+				strconv.Itoa(result.Outliers),
+				strconv.FormatBool(result.Unstable),
+				strings.Join(result.Tags, tagSeparator),
+				baseline,
+			}); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// ReadCSV reads results written by WriteCSV. Columns are matched by their
+// header, so they may be reordered and unknown columns are ignored. Only the
+// name, sample and ns_op columns are required, and rows without a baseline
+// belong to the latest results. The rows of a result must list its samples in
+// the order of their index, as written by WriteCSV.
+func ReadCSV(r io.Reader) (Results, error) {
+	in := csv.NewReader(bufio.NewReader(r))
+	in.FieldsPerRecord = -1
+
+	header, err := in.Read()
+	switch {
+	case err == io.EOF:
+		return make(Results), nil
+	case err != nil:
+		return nil, err
+	}
+
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		columns[name] = i
+	}
+	for _, required := range []string{"name", "sample", "ns_op"} {
+		if _, ok := columns[required]; !ok {
+			return nil, fmt.Errorf("missing column %q", required)
+		}
+	}
+
+	results := make(Results)
+	for line := 2; ; line++ {
+		row, err := in.Read()
+		switch {
+		case err == io.EOF:
+			return results, nil
+		case err != nil:
+			return nil, err
+		}
+
+		if err := readCSVRow(results, columns, row); err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+	}
+}
+
+// readCSVRow adds a single sample row to the results. A row with an empty
+// sample index only carries the details of a result without samples.
+func readCSVRow(results Results, columns map[string]int, row []string) error {
+	cell := func(name string) string {
+		if i, ok := columns[name]; ok && i < len(row) {
+			return row[i]
+		}
+		return ""
+	}
+
+	baseline := cell("baseline")
+	result := results.get(baseline)[cell("name")]
+	result.Name = cell("name")
+
+	var err error
+	if v := cell("sample"); v != "" {
+		var index int
+		if index, err = strconv.Atoi(v); err != nil || index < 0 {
+			return fmt.Errorf("invalid sample index %q", v)
+		}
+
+		if result.Samples, err = setSampleAt(result.Samples, index, cell("ns_op")); err != nil {
+			return err
+		}
+		if result.Allocs, err = setSampleAt(result.Allocs, index, cell("allocs_op")); err != nil {
+			return err
+		}
+		if result.Bytes, err = setSampleAt(result.Bytes, index, cell("bytes_op")); err != nil {
+			return err
+		}
+	}
+	if v := cell("timestamp"); v != "" {
+		if result.Timestamp, err = strconv.ParseInt(v, 10, 64); err != nil {
+			return err
+		}
+	}
+	if v := cell("outliers"); v != "" {
+		if result.Outliers, err = strconv.Atoi(v); err != nil {
+			return err
+		}
+	}
+	if v := cell("unstable"); v != "" {
+		if result.Unstable, err = strconv.ParseBool(v); err != nil {
+			return err
+		}
+	}
+
+	if v := cell("tags"); v != "" {
+		result.Tags = strings.Split(v, tagSeparator)
+	}
+
+	results.put(baseline, result)
+	return nil
+}
+
+// setSampleAt parses the value and appends it as the sample at the given index,
+// which must follow the samples read so far. Empty values leave the slice untouched.
+func setSampleAt(samples []float64, index int, value string) ([]float64, error) {
+	if value == "" {
+		return samples, nil
+	}
+
+	v, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return samples, err
+	}
+
+	if index != len(samples) {
+		return samples, fmt.Errorf("sample index %d does not follow the %d samples read", index, len(samples))
+	}
+	return append(samples, v), nil
+}
+
+// formatSampleAt formats the sample at the index, or an empty cell if missing.
+func formatSampleAt(samples []float64, index int) string {
+	if index >= len(samples) {
+		return ""
+	}
+	return formatFloat(samples[index])
+}
+
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
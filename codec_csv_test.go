@@ -0,0 +1,106 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root
+
+package bench
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCSVRoundTrip(t *testing.T) {
+	results := Results{
+		LatestBaseline: {
+			"b": {Name: "b", Samples: []float64{1.5, 2.25}, Allocs: []float64{1, 2}, Bytes: []float64{16, 32}, Outliers: 1, Unstable: true, Tags: []string{"io", "slow"}, Timestamp: 42},
+			"a": {Name: "a", Samples: []float64{100}, Allocs: []float64{0}, Bytes: []float64{0}, Timestamp: 7},
+		},
+		"main": {"a": {Name: "a", Samples: []float64{90}, Timestamp: 3}},
+	}
+
+	var buffer bytes.Buffer
+	assert.NoError(t, WriteCSV(&buffer, results))
+
+	lines := strings.Split(strings.TrimSpace(buffer.String()), "\n")
+	assert.Equal(t, "name,timestamp,sample,ns_op,allocs_op,bytes_op,outliers,unstable,tags,baseline", lines[0])
+	assert.Equal(t, "a,7,0,100,0,0,0,false,,latest", lines[1])
+	assert.Equal(t, "b,42,0,1.5,1,16,1,true,io;slow,latest", lines[2])
+	assert.Equal(t, "a,3,0,90,,,0,false,,main", lines[4])
+	assert.Len(t, lines, 5)
+
+	loaded, err := ReadCSV(&buffer)
+	assert.NoError(t, err)
+	assert.Equal(t, results, loaded)
+}
+
+func TestCSVResultWithoutSamples(t *testing.T) {
+	results := Results{LatestBaseline: {
+		"a": {Name: "a", Outliers: 2, Tags: []string{"io"}, Timestamp: 7},
+	}}
+
+	var buffer bytes.Buffer
+	assert.NoError(t, WriteCSV(&buffer, results))
+
+	lines := strings.Split(strings.TrimSpace(buffer.String()), "\n")
+	assert.Equal(t, []string{"name,timestamp,sample,ns_op,allocs_op,bytes_op,outliers,unstable,tags,baseline", "a,7,,,,,2,false,io,latest"}, lines)
+
+	loaded, err := ReadCSV(&buffer)
+	assert.NoError(t, err)
+	assert.Equal(t, results, loaded)
+}
+
+func TestReadCSVByHeader(t *testing.T) {
+	input := "ns_op,note,sample,name\n20,x,0,a\n10,y,1,a\n"
+
+	loaded, err := ReadCSV(strings.NewReader(input))
+	assert.NoError(t, err)
+	assert.Equal(t, []float64{20, 10}, loaded[LatestBaseline]["a"].Samples)
+	assert.Nil(t, loaded[LatestBaseline]["a"].Allocs)
+}
+
+func TestReadCSVErrors(t *testing.T) {
+	_, err := ReadCSV(strings.NewReader("name,sample\na,0\n"))
+	assert.Error(t, err)
+
+	_, err = ReadCSV(strings.NewReader("name,sample,ns_op\na,-1,1\n"))
+	assert.Error(t, err)
+
+	_, err = ReadCSV(strings.NewReader("name,sample,ns_op\na,x,1\n"))
+	assert.Error(t, err)
+
+	_, err = ReadCSV(strings.NewReader("name,sample,ns_op\na,0,fast\n"))
+	assert.Error(t, err)
+
+	// Sample indexes must be sequential, without gaps or repeats
+	for _, input := range []string{
+		"name,sample,ns_op\na,1000000000000,1\n",
+		"name,sample,ns_op\na,0,1\na,2,1\n",
+		"name,sample,ns_op\na,0,1\na,0,2\n",
+		"name,sample,ns_op,allocs_op\na,0,1,\na,1,1,5\n",
+	} {
+		_, err = ReadCSV(strings.NewReader(input))
+		assert.ErrorContains(t, err, "sample index", input)
+	}
+
+	loaded, err := ReadCSV(strings.NewReader(""))
+	assert.NoError(t, err)
+	assert.Empty(t, loaded)
+}
+
+func TestCSVCodec(t *testing.T) {
+	file := "test_codec.csv"
+	defer removeResults(file)
+
+	b := &B{config: config{filename: file}}
+	b.config.normalize()
+	assert.IsType(t, csvCodec{}, b.codec)
+
+	b.saveResult(Result{Name: "bench", Samples: []float64{1, 2, 3}, Allocs: []float64{0, 1, 1}, Timestamp: 5})
+	loaded, err := b.loadResults()
+	assert.NoError(t, err)
+	assert.Equal(t, []float64{1, 2, 3}, loaded[LatestBaseline]["bench"].Samples)
+	assert.Equal(t, []float64{0, 1, 1}, loaded[LatestBaseline]["bench"].Allocs)
+	assert.Equal(t, int64(5), loaded[LatestBaseline]["bench"].Timestamp)
+}
@@ -0,0 +1,285 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root
+
+package bench
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+)
+
+// journalMagic identifies a journal file, and the digit before the newline the
+// version of its record format.
+var journalMagic = []byte("BENCHJ1\n")
+
+// maxRecordSize bounds the payload length read from a record header, so that a
+// corrupt length does not cause a huge allocation.
+const maxRecordSize = 1 << 28
+
+// journalCodec stores results as an append-only journal of records, each made
+// of a little-endian uint32 payload length, a CRC-32 (Castagnoli) checksum of
+// the payload, the gob-encoded result with packed samples and its baseline, and
+// the payload length again, so the last record can be found from the end. The
+// last record for a name in a baseline wins.
+type journalCodec struct {
+	fileStore
+}
+
+var crcTable = crc32.MakeTable(crc32.Castagnoli)
+
+func (journalCodec) Load(filename string) (Results, error) {
+	return readWithBackup(filename, func(r io.Reader) (Results, error) {
+		results := make(Results)
+		_, err := scanJournal(bufio.NewReader(r), results.put)
+		return results, err
+	})
+}
+
+// save compacts the results into a new journal with a single record per
+// benchmark, replacing the file atomically.
+func (journalCodec) Save(filename string, results Results) error {
+	return writeAtomic(filename, func(w io.Writer) error {
+		if _, err := w.Write(journalMagic); err != nil {
+			return err
+		}
+		for baseline, stored := range results {
+			for _, result := range stored {
+				if err := writeRecord(w, baseline, result); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+}
+
+// append adds results to the end of the journal without rewriting it. Only the
+// last record is validated, and a truncated record left behind by a crash is
+// discarded first.
+func (c journalCodec) Append(filename, baseline string, results []Result) error {
+	if err := c.prepare(filename); err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(filename, os.O_RDWR, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	end, err := journalTail(f)
+	if err != nil {
+		return err
+	}
+	if err := f.Truncate(end); err != nil {
+		return err
+	}
+	if _, err := f.Seek(end, io.SeekStart); err != nil {
+		return err
+	}
+
+	w := bufio.NewWriter(f)
+	for _, result := range results {
+		if err := writeRecord(w, baseline, result); err != nil {
+			return err
+		}
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	return f.Sync()
+}
+
+// prepare makes sure the file is a journal in the current record format. A new
+// journal, or one whose header was never completely written, gets its header
+// through an atomic write.
+func (journalCodec) prepare(filename string) error {
+	f, err := os.Open(filename)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		return writeJournalHeader(filename)
+	case err != nil:
+		return err
+	}
+
+	magic := make([]byte, len(journalMagic))
+	n, err := io.ReadFull(f, magic)
+	f.Close()
+	switch {
+	case err != nil && err != io.EOF && err != io.ErrUnexpectedEOF:
+		return err
+	case n < len(magic) && bytes.HasPrefix(journalMagic, magic[:n]):
+		return writeJournalHeader(filename)
+	}
+
+	return checkJournalHeader(magic[:n])
+}
+
+// writeJournalHeader atomically replaces the file with an empty journal.
+func writeJournalHeader(filename string) error {
+	return writeAtomic(filename, func(w io.Writer) error {
+		_, err := w.Write(journalMagic)
+		return err
+	})
+}
+
+// journalTail returns the offset just past the last complete record, reading
+// only the final record through its length trailer. When the tail does not hold
+// a valid record, as after a crash in the middle of an append, the whole journal
+// is scanned instead.
+func journalTail(f *os.File) (int64, error) {
+	info, err := f.Stat()
+	if err != nil {
+		return 0, err
+	}
+
+	size, start := info.Size(), int64(len(journalMagic))
+	if size == start {
+		return size, nil
+	}
+
+	if size > start+12 {
+		var trailer [4]byte
+		if _, err := f.ReadAt(trailer[:], size-4); err != nil {
+			return 0, err
+		}
+
+		length := int64(binary.LittleEndian.Uint32(trailer[:]))
+		if offset := size - 12 - length; length <= maxRecordSize && offset >= start {
+			record := make([]byte, 8+length)
+			if _, err := f.ReadAt(record, offset); err != nil {
+				return 0, err
+			}
+			if int64(binary.LittleEndian.Uint32(record[0:4])) == length &&
+				binary.LittleEndian.Uint32(record[4:8]) == crc32.Checksum(record[8:], crcTable) {
+				return size, nil
+			}
+		}
+	}
+
+	return scanJournal(bufio.NewReader(io.NewSectionReader(f, 0, size)), nil)
+}
+
+// Compact rewrites a journal results file so that it holds a single record per
+// benchmark, dropping the superseded history.
+func Compact(filename string) error {
+	unlock, err := lockResults(filename)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	results, err := journalCodec{}.Load(filename)
+	if err != nil {
+		return err
+	}
+	return journalCodec{}.Save(filename, results)
+}
+
+// writeRecord writes a single length-prefixed and checksummed record, followed
+// by its length trailer.
+func writeRecord(w io.Writer, baseline string, result Result) error {
+	packed := packResult(result)
+	if baseline != LatestBaseline {
+		packed.Baseline = baseline
+	}
+
+	var payload bytes.Buffer
+	if err := gob.NewEncoder(&payload).Encode(packed); err != nil {
+		return err
+	}
+
+	var header [8]byte
+	var trailer [4]byte
+	binary.LittleEndian.PutUint32(header[0:4], uint32(payload.Len()))
+	binary.LittleEndian.PutUint32(header[4:8], crc32.Checksum(payload.Bytes(), crcTable))
+	binary.LittleEndian.PutUint32(trailer[:], uint32(payload.Len()))
+	if _, err := w.Write(header[:]); err != nil {
+		return err
+	}
+	if _, err := w.Write(payload.Bytes()); err != nil {
+		return err
+	}
+	_, err := w.Write(trailer[:])
+	return err
+}
+
+// checkJournalHeader verifies that the header is the one of a journal in the
+// current record format.
+func checkJournalHeader(magic []byte) error {
+	switch {
+	case bytes.Equal(magic, journalMagic):
+		return nil
+	case len(magic) != len(journalMagic) || !bytes.HasPrefix(magic, journalMagic[:6]):
+		return errors.New("not a journal file")
+	default:
+		return fmt.Errorf("%w %q", ErrUnsupportedVersion, bytes.TrimSpace(magic))
+	}
+}
+
+// scanJournal reads the records of a journal, calling fn with the baseline and
+// result of each one when it is not nil, and returns the offset just past the
+// last complete record. A truncated or partially written final record is
+// tolerated and excluded.
+func scanJournal(r *bufio.Reader, fn func(string, Result)) (int64, error) {
+	magic := make([]byte, len(journalMagic))
+	if n, err := io.ReadFull(r, magic); n == 0 && err == io.EOF {
+		return 0, nil // Empty journal
+	}
+
+	if err := checkJournalHeader(magic); err != nil {
+		return 0, err
+	}
+
+	end := int64(len(journalMagic))
+	var header [8]byte
+	var trailer [4]byte
+	for {
+		if _, err := io.ReadFull(r, header[:]); err != nil {
+			return end, nil // End of journal, or a truncated header
+		}
+
+		size := binary.LittleEndian.Uint32(header[0:4])
+		if size > maxRecordSize {
+			return end, fmt.Errorf("corrupt journal record at offset %d", end)
+		}
+
+		payload := make([]byte, size)
+		if _, err := io.ReadFull(r, payload); err != nil {
+			return end, nil // Truncated payload of the last record
+		}
+
+		if _, err := io.ReadFull(r, trailer[:]); err != nil {
+			return end, nil // Truncated trailer of the last record
+		}
+
+		if crc32.Checksum(payload, crcTable) != binary.LittleEndian.Uint32(header[4:8]) ||
+			binary.LittleEndian.Uint32(trailer[:]) != size {
+			if _, err := r.Peek(1); err == io.EOF {
+				return end, nil // Partially written last record
+			}
+			return end, fmt.Errorf("corrupt journal record at offset %d", end)
+		}
+
+		if fn != nil {
+			var packed packedResult
+			if err := gob.NewDecoder(bytes.NewReader(payload)).Decode(&packed); err != nil {
+				return end, err
+			}
+
+			result, err := packed.unpack()
+			if err != nil {
+				return end, err
+			}
+			fn(packed.Baseline, result)
+		}
+		end += int64(len(header)) + int64(size) + int64(len(trailer))
+	}
+}
@@ -0,0 +1,144 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root
+
+package bench
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJournalAppendAndLoad(t *testing.T) {
+	file := "test_codec.journal"
+	defer removeResults(file)
+
+	b := &B{config: config{filename: file}}
+	b.config.normalize()
+	assert.IsType(t, journalCodec{}, b.codec)
+
+	b.saveResult(Result{Name: "a", Samples: []float64{1, 2, 3}, Timestamp: 1})
+	b.saveResult(Result{Name: "b", Samples: []float64{4, 5, 6}, Timestamp: 2})
+	b.saveResult(Result{Name: "a", Samples: []float64{7, 8, 9}, Timestamp: 3})
+
+	loaded, err := journalCodec{}.Load(file)
+	assert.NoError(t, err)
+	assert.Len(t, loaded[LatestBaseline], 2)
+	assert.Equal(t, int64(3), loaded[LatestBaseline]["a"].Timestamp, "last record should win")
+	assert.Equal(t, []float64{4, 5, 6}, loaded[LatestBaseline]["b"].Samples)
+}
+
+func TestJournalToleratesTruncatedRecord(t *testing.T) {
+	file := "test_truncated.journal"
+	defer removeResults(file)
+
+	codec := journalCodec{}
+	assert.NoError(t, codec.Append(file, LatestBaseline, []Result{{Name: "a", Timestamp: 1}}))
+	assert.NoError(t, codec.Append(file, LatestBaseline, []Result{{Name: "b", Timestamp: 2}}))
+
+	// Simulate a crash in the middle of writing the last record
+	info, _ := os.Stat(file)
+	assert.NoError(t, os.Truncate(file, info.Size()-3))
+
+	loaded, err := codec.Load(file)
+	assert.NoError(t, err)
+	assert.Len(t, loaded[LatestBaseline], 1)
+	assert.Contains(t, loaded[LatestBaseline], "a")
+
+	// Appending discards the truncated record first
+	assert.NoError(t, codec.Append(file, LatestBaseline, []Result{{Name: "c", Timestamp: 3}}))
+	loaded, err = codec.Load(file)
+	assert.NoError(t, err)
+	assert.Len(t, loaded[LatestBaseline], 2)
+	assert.Contains(t, loaded[LatestBaseline], "c")
+}
+
+func TestJournalDetectsCorruption(t *testing.T) {
+	file := "test_corrupt.journal"
+	defer removeResults(file)
+
+	codec := journalCodec{}
+	assert.NoError(t, codec.Append(file, LatestBaseline, []Result{{Name: "a"}, {Name: "b"}}))
+
+	// Flip a byte in the middle of the first record's payload
+	data, _ := os.ReadFile(file)
+	data[len(journalMagic)+12] ^= 0xff
+	assert.NoError(t, os.WriteFile(file, data, 0644))
+
+	_, err := codec.Load(file)
+	assert.Error(t, err)
+
+	// Appending only validates the last record, so loading still reports it
+	assert.NoError(t, codec.Append(file, LatestBaseline, []Result{{Name: "c"}}))
+	_, err = codec.Load(file)
+	assert.Error(t, err)
+
+	// Not a journal at all
+	assert.NoError(t, os.WriteFile(file, []byte("bad"), 0644))
+	_, err = codec.Load(file)
+	assert.Error(t, err)
+}
+
+func TestJournalCompact(t *testing.T) {
+	file := "test_compact.journal"
+	defer removeResults(file)
+
+	codec := journalCodec{}
+	for i := 0; i < 10; i++ {
+		assert.NoError(t, codec.Append(file, LatestBaseline, []Result{{Name: "a", Samples: []float64{float64(i)}}}))
+	}
+
+	before, _ := os.Stat(file)
+	assert.NoError(t, Compact(file))
+	after, _ := os.Stat(file)
+	assert.Less(t, after.Size(), before.Size())
+
+	loaded, err := codec.Load(file)
+	assert.NoError(t, err)
+	assert.Equal(t, []float64{9}, loaded[LatestBaseline]["a"].Samples)
+}
+
+func TestJournalRecoversPartialHeader(t *testing.T) {
+	file := "test_header.journal"
+	defer removeResults(file)
+
+	// Simulate a crash in the middle of writing the header of a new journal
+	assert.NoError(t, os.WriteFile(file, journalMagic[:3], 0644))
+	assert.NoError(t, journalCodec{}.Append(file, LatestBaseline, []Result{{Name: "a"}}))
+
+	loaded, err := journalCodec{}.Load(file)
+	assert.NoError(t, err)
+	assert.Contains(t, loaded[LatestBaseline], "a")
+}
+
+func TestJournalBaselines(t *testing.T) {
+	file := "test_baselines.journal"
+	defer removeResults(file)
+
+	codec := journalCodec{}
+	assert.NoError(t, codec.Append(file, LatestBaseline, []Result{{Name: "a", Timestamp: 1}}))
+	assert.NoError(t, codec.Append(file, "main", []Result{{Name: "a", Timestamp: 2}}))
+
+	loaded, err := codec.Load(file)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), loaded[LatestBaseline]["a"].Timestamp)
+	assert.Equal(t, int64(2), loaded["main"]["a"].Timestamp)
+
+	assert.NoError(t, Compact(file))
+	compacted, err := codec.Load(file)
+	assert.NoError(t, err)
+	assert.Equal(t, loaded, compacted)
+}
+
+func BenchmarkJournalAppend(b *testing.B) {
+	file := "bench_append.journal"
+	defer removeResults(file)
+
+	result := Result{Name: "a", Samples: make([]float64, 100)}
+	for b.Loop() {
+		if err := (journalCodec{}).Append(file, LatestBaseline, []Result{result}); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
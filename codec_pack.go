@@ -0,0 +1,148 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root
+
+package bench
+
+import (
+	"compress/gzip"
+	"encoding/binary"
+	"errors"
+	"io"
+	"math"
+)
+
+// packedResult is the gob representation of a Result, with the samples packed
+// into compact byte strings. The unpacked sample fields keep their names so
+// that files written before packing was introduced still decode.
+type packedResult struct {
+	Name          string
+	Samples       []float64 // unpacked samples of older files
+	Allocs        []float64 // unpacked samples of older files
+	Bytes         []float64 // unpacked samples of older files
+	PackedSamples []byte
+	PackedAllocs  []byte
+	PackedBytes   []byte
+	Outliers      int
+	Unstable      bool
+	Tags          []string
+	Timestamp     int64
+	Baseline      string // baseline of a journal record, empty for the latest
+}
+
+// packResult converts a result to its packed gob representation.
+func packResult(result Result) packedResult {
+	return packedResult{
+		Name:          result.Name,
+		PackedSamples: packSamples(result.Samples),
+		PackedAllocs:  packSamples(result.Allocs),
+		PackedBytes:   packSamples(result.Bytes),
+		Outliers:      result.Outliers,
+		Unstable:      result.Unstable,
+		Tags:          result.Tags,
+		Timestamp:     result.Timestamp,
+	}
+}
+
+// unpack converts the packed representation back to a result, accepting the
+// unpacked samples of older files as well.
+func (p packedResult) unpack() (result Result, err error) {
+	result = Result{
+		Name:      p.Name,
+		Samples:   p.Samples,
+		Allocs:    p.Allocs,
+		Bytes:     p.Bytes,
+		Outliers:  p.Outliers,
+		Unstable:  p.Unstable,
+		Tags:      p.Tags,
+		Timestamp: p.Timestamp,
+	}
+
+	if p.PackedSamples != nil {
+		if result.Samples, err = unpackSamples(p.PackedSamples); err != nil {
+			return
+		}
+	}
+	if p.PackedAllocs != nil {
+		if result.Allocs, err = unpackSamples(p.PackedAllocs); err != nil {
+			return
+		}
+	}
+	if p.PackedBytes != nil {
+		if result.Bytes, err = unpackSamples(p.PackedBytes); err != nil {
+			return
+		}
+	}
+	return
+}
+
+// packSamples encodes the samples losslessly as a uvarint count followed by the
+// zigzag varint deltas between the IEEE 754 bits of consecutive samples.
+// Repeated values, such as allocation counts, take a single byte each.
+func packSamples(samples []float64) []byte {
+	if samples == nil {
+		return nil
+	}
+
+	out := binary.AppendUvarint(make([]byte, 0, 1+len(samples)*4), uint64(len(samples)))
+	prev := uint64(0)
+	for _, v := range samples {
+		bits := math.Float64bits(v)
+		out = binary.AppendVarint(out, int64(bits-prev))
+		prev = bits
+	}
+	return out
+}
+
+// unpackSamples decodes samples encoded by packSamples.
+func unpackSamples(data []byte) ([]float64, error) {
+	count, n := binary.Uvarint(data)
+	if n <= 0 || count > uint64(len(data)) {
+		return nil, errors.New("corrupt packed samples")
+	}
+
+	data = data[n:]
+	out := make([]float64, 0, count)
+	prev := uint64(0)
+	for i := uint64(0); i < count; i++ {
+		delta, n := binary.Varint(data)
+		if n <= 0 {
+			return nil, errors.New("corrupt packed samples")
+		}
+
+		data = data[n:]
+		prev += uint64(delta)
+		out = append(out, math.Float64frombits(prev))
+	}
+	return out, nil
+}
+
+// compress wraps the writer in a gzip stream when enabled.
+func compress(enabled bool, write func(io.Writer) error) func(io.Writer) error {
+	if !enabled {
+		return write
+	}
+
+	return func(w io.Writer) error {
+		zw := gzip.NewWriter(w)
+		if err := write(zw); err != nil {
+			return err
+		}
+		return zw.Close()
+	}
+}
+
+// decompress wraps the reader in a gzip stream when enabled.
+func decompress(enabled bool, decode func(io.Reader) (Results, error)) func(io.Reader) (Results, error) {
+	if !enabled {
+		return decode
+	}
+
+	return func(r io.Reader) (Results, error) {
+		zr, err := gzip.NewReader(r)
+		if err != nil {
+			return nil, err
+		}
+		defer zr.Close()
+		return decode(zr)
+	}
+}
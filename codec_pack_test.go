@@ -0,0 +1,103 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root
+
+package bench
+
+import (
+	"bytes"
+	"encoding/gob"
+	"math"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPackSamples(t *testing.T) {
+	for _, samples := range [][]float64{
+		{},
+		{0},
+		{1.5, 1.5, 1.5},
+		{123.456, 120.1, 130.75, 0, -1, math.Inf(1), math.MaxFloat64, math.SmallestNonzeroFloat64},
+	} {
+		out, err := unpackSamples(packSamples(samples))
+		assert.NoError(t, err)
+		assert.Equal(t, samples, out)
+	}
+
+	assert.Nil(t, packSamples(nil))
+}
+
+func TestPackSamplesCompact(t *testing.T) {
+	allocs := make([]float64, 100)
+	for i := range allocs {
+		allocs[i] = 3
+	}
+
+	// Repeated values take a byte each, plus the count and the first value
+	assert.Less(t, len(packSamples(allocs)), 120)
+}
+
+func TestUnpackSamplesCorrupt(t *testing.T) {
+	_, err := unpackSamples(nil)
+	assert.Error(t, err)
+
+	_, err = unpackSamples([]byte{3, 0x80})
+	assert.Error(t, err)
+
+	packed := packSamples([]float64{1, 2, 3})
+	_, err = unpackSamples(packed[:len(packed)-1])
+	assert.Error(t, err)
+}
+
+func TestGobCodecLegacy(t *testing.T) {
+	file := "test_legacy.gob"
+	defer removeResults(file)
+
+	// Files written before samples were packed hold plain results
+	legacy := map[string]Result{
+		"bench": {Name: "bench", Samples: []float64{1, 2, 3}, Allocs: []float64{0, 1, 1}, Timestamp: 7},
+	}
+
+	var buffer bytes.Buffer
+	assert.NoError(t, gob.NewEncoder(&buffer).Encode(legacy))
+	assert.NoError(t, os.WriteFile(file, buffer.Bytes(), 0644))
+
+	loaded, err := gobCodec{}.Load(file)
+	assert.NoError(t, err)
+	assert.Equal(t, Results{LatestBaseline: legacy}, loaded)
+}
+
+func TestCompressedCodecs(t *testing.T) {
+	results := Results{LatestBaseline: {
+		"bench": {Name: "bench", Samples: []float64{1.25, 2, 3}, Allocs: []float64{0, 1, 1}, Bytes: []float64{0, 8, 8}, Outliers: 1, Tags: []string{"io"}, Timestamp: 9},
+	}}
+
+	for _, file := range []string{"test_codec.json.gz", "test_codec.gob.gz"} {
+		defer removeResults(file)
+
+		codec := codecFor(file)
+		assert.NoError(t, codec.Save(file, results))
+
+		// Files start with the gzip magic number
+		data, err := os.ReadFile(file)
+		assert.NoError(t, err)
+		assert.Equal(t, []byte{0x1f, 0x8b}, data[:2])
+
+		loaded, err := codec.Load(file)
+		assert.NoError(t, err)
+		assert.Equal(t, results, loaded)
+	}
+
+	assert.IsType(t, jsonCodec{}, codecFor("results.json.gz"))
+	assert.IsType(t, gobCodec{}, codecFor("results.gob.gz"))
+}
+
+func TestCompressedCodecLoadError(t *testing.T) {
+	file := "bad.json.gz"
+	os.WriteFile(file, []byte("not gzip"), 0644)
+	defer removeResults(file)
+
+	_, err := jsonCodec{compress: true}.Load(file)
+	assert.Error(t, err)
+}
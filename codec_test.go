@@ -4,36 +4,42 @@
 package bench
 
 import (
+	"errors"
+	"fmt"
+	"maps"
 	"os"
+	"path/filepath"
+	"sync"
 	"testing"
+	"time"
 )
 
 func TestSaveLoadResult(t *testing.T) {
 	file := "test_codec.json"
-	defer os.Remove(file)
+	defer removeResults(file)
 	b := &B{config: config{filename: file, codec: jsonCodec{}}}
 	res := Result{Name: "bench", Samples: []float64{1, 2, 3}, Allocs: []float64{0, 1, 1}, Timestamp: 123}
 	b.saveResult(res)
-	loaded := b.loadResults()
-	if loaded["bench"].Timestamp != 123 {
+	loaded, err := b.loadResults()
+	if err != nil || loaded[LatestBaseline]["bench"].Timestamp != 123 {
 		t.Fatalf("expected timestamp 123")
 	}
-	if len(loaded["bench"].Allocs) != 3 {
+	if len(loaded[LatestBaseline]["bench"].Allocs) != 3 {
 		t.Fatalf("expected alloc samples to be persisted")
 	}
 }
 
 func TestGobCodec(t *testing.T) {
 	file := "test_codec.gob"
-	defer os.Remove(file)
+	defer removeResults(file)
 	b := &B{config: config{filename: file, codec: gobCodec{}}}
 	res := Result{Name: "bench", Samples: []float64{1, 2, 3}, Allocs: []float64{0, 1, 1}, Timestamp: 321}
 	b.saveResult(res)
-	loaded := b.loadResults()
-	if loaded["bench"].Timestamp != 321 {
+	loaded, err := b.loadResults()
+	if err != nil || loaded[LatestBaseline]["bench"].Timestamp != 321 {
 		t.Fatalf("expected timestamp 321")
 	}
-	if len(loaded["bench"].Allocs) != 3 {
+	if len(loaded[LatestBaseline]["bench"].Allocs) != 3 {
 		t.Fatalf("expected alloc samples to be persisted")
 	}
 }
@@ -41,19 +47,252 @@ func TestGobCodec(t *testing.T) {
 func TestJSONCodecLoadError(t *testing.T) {
 	file := "bad.json"
 	os.WriteFile(file, []byte("bad"), 0644)
-	defer os.Remove(file)
-	res := jsonCodec{}.load(file)
-	if len(res) != 0 {
-		t.Fatalf("expected empty result")
+	defer removeResults(file)
+	res, err := jsonCodec{}.Load(file)
+	if err == nil || len(res) != 0 {
+		t.Fatalf("expected decode error")
 	}
 }
 
 func TestGobCodecLoadError(t *testing.T) {
 	file := "bad.gob"
 	os.WriteFile(file, []byte("bad"), 0644)
-	defer os.Remove(file)
-	res := gobCodec{}.load(file)
-	if len(res) != 0 {
-		t.Fatalf("expected empty result")
+	defer removeResults(file)
+	res, err := gobCodec{}.Load(file)
+	if err == nil || len(res) != 0 {
+		t.Fatalf("expected decode error")
 	}
 }
+
+func TestAtomicSaveKeepsBackup(t *testing.T) {
+	for _, file := range []string{"test_atomic.json", "test_atomic.gob"} {
+		defer removeResults(file)
+		b := &B{config: config{filename: file}}
+		b.config.normalize()
+
+		b.saveResult(Result{Name: "first", Timestamp: 1})
+		b.saveResult(Result{Name: "second", Timestamp: 2})
+
+		backup, _ := b.codec.Load(file + backupSuffix)
+		if _, ok := backup[LatestBaseline]["second"]; ok || backup[LatestBaseline]["first"].Timestamp != 1 {
+			t.Fatalf("expected backup to contain the previous version")
+		}
+
+		matches, _ := filepath.Glob(file + ".tmp*")
+		if len(matches) != 0 {
+			t.Fatalf("expected temporary files to be removed, got %v", matches)
+		}
+	}
+}
+
+func TestLoadRecoversFromBackup(t *testing.T) {
+	for _, file := range []string{"test_recover.json", "test_recover.gob"} {
+		defer removeResults(file)
+		b := &B{config: config{filename: file}}
+		b.config.normalize()
+
+		b.saveResult(Result{Name: "first", Timestamp: 1})
+		b.saveResult(Result{Name: "second", Timestamp: 2})
+
+		// Simulate a crash that truncated the results file
+		os.WriteFile(file, []byte("trunc"), 0644)
+		loaded, _ := b.loadResults()
+		if loaded[LatestBaseline]["first"].Timestamp != 1 {
+			t.Fatalf("expected results to be recovered from backup")
+		}
+
+		// Simulate a crash between the backup and the final rename
+		os.Remove(file)
+		loaded, _ = b.loadResults()
+		if loaded[LatestBaseline]["first"].Timestamp != 1 {
+			t.Fatalf("expected results to be recovered from backup")
+		}
+	}
+}
+
+func TestMissingFileLoadsEmpty(t *testing.T) {
+	res, err := jsonCodec{}.Load("missing.json")
+	if err != nil || len(res) != 0 {
+		t.Fatalf("expected empty result without error")
+	}
+}
+
+func TestSaveRefusesToOverwriteCorruptFile(t *testing.T) {
+	file := "test_refuse.json"
+	defer removeResults(file)
+	os.WriteFile(file, []byte("bad"), 0644)
+
+	b := &B{config: config{filename: file, codec: jsonCodec{}}}
+	b.saveResult(Result{Name: "bench"})
+
+	data, _ := os.ReadFile(file)
+	if string(data) != "bad" {
+		t.Fatalf("expected corrupt file to be left untouched")
+	}
+}
+
+func TestConcurrentSavesAreMerged(t *testing.T) {
+	file := "test_concurrent.gob"
+	defer removeResults(file)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			b := &B{config: config{filename: file, codec: gobCodec{}}}
+			for j := 0; j < 5; j++ {
+				b.saveResult(Result{Name: fmt.Sprintf("bench-%d-%d", i, j)})
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	loaded, err := gobCodec{}.Load(file)
+	if err != nil || len(loaded[LatestBaseline]) != 40 {
+		t.Fatalf("expected 40 merged results, got %d (%v)", len(loaded[LatestBaseline]), err)
+	}
+}
+
+func TestSaveResultCheckpoints(t *testing.T) {
+	file := "test_checkpoint.json"
+	defer removeResults(file)
+
+	b := &B{config: config{filename: file, codec: jsonCodec{}, checkpoint: time.Hour}}
+	b.saveResult(Result{Name: "first"})
+	b.saveResult(Result{Name: "second"})
+
+	// The first result is written immediately, the second waits for a checkpoint
+	loaded, _ := jsonCodec{}.Load(file)
+	if _, ok := loaded[LatestBaseline]["first"]; !ok {
+		t.Fatalf("expected first result to be written")
+	}
+	if _, ok := loaded[LatestBaseline]["second"]; ok {
+		t.Fatalf("expected second result to be pending")
+	}
+	if _, ok := b.previous()[LatestBaseline]["second"]; !ok {
+		t.Fatalf("expected second result to be visible in memory")
+	}
+
+	b.flush()
+	loaded, _ = jsonCodec{}.Load(file)
+	if _, ok := loaded[LatestBaseline]["second"]; !ok {
+		t.Fatalf("expected second result to be written after flush")
+	}
+}
+
+func TestRunFlushesOnPanic(t *testing.T) {
+	file := "test_flush_panic.json"
+	defer removeResults(file)
+
+	func() {
+		defer func() { recover() }()
+		Run(func(b *B) {
+			b.Run("a", func(i int) {})
+			b.Run("b", func(i int) {})
+			panic("boom")
+		}, WithFile(file), WithSamples(2), WithCheckpoint(time.Hour))
+	}()
+
+	loaded, _ := jsonCodec{}.Load(file)
+	if len(loaded[LatestBaseline]) != 2 {
+		t.Fatalf("expected pending results to be flushed, got %d", len(loaded[LatestBaseline]))
+	}
+}
+
+// memoryCodec keeps results in memory, keyed by filename.
+type memoryCodec struct {
+	files map[string]Results
+}
+
+func (m *memoryCodec) Load(filename string) (Results, error) {
+	out := make(Results)
+	for k, v := range m.files[filename] {
+		out[k] = maps.Clone(v)
+	}
+	return out, nil
+}
+
+func (m *memoryCodec) Save(filename string, results Results) error {
+	m.files[filename] = results
+	return nil
+}
+
+func TestWithCodec(t *testing.T) {
+	file := "test_custom.mem"
+	defer removeResults(file)
+
+	codec := &memoryCodec{files: make(map[string]Results)}
+	Run(func(b *B) {
+		b.Run("bench", func(i int) {})
+	}, WithCodec(codec), WithFile(file), WithSamples(2))
+
+	if _, ok := codec.files[file][LatestBaseline]["bench"]; !ok {
+		t.Fatalf("expected result to be saved through the custom codec")
+	}
+	if _, err := os.Stat(file); err == nil {
+		t.Fatalf("expected no results file on disk")
+	}
+	if _, err := os.Stat(file + lockSuffix); err == nil {
+		t.Fatalf("expected no lock file for a codec which is not backed by files")
+	}
+}
+
+// brokenCodec fails to decode any results.
+type brokenCodec struct{}
+
+func (brokenCodec) Load(string) (Results, error) {
+	return nil, errors.New("broken")
+}
+
+func (brokenCodec) Save(string, Results) error {
+	return nil
+}
+
+func TestRecoverRenameRequiresMover(t *testing.T) {
+	file := "test_custom_corrupt.mem"
+	os.WriteFile(file, []byte("bad"), 0644)
+	defer removeResults(file)
+	defer os.Remove(file + corruptSuffix)
+
+	b := &B{config: config{filename: file, codec: brokenCodec{}, recovery: RecoverRename}}
+	if err := b.open(); err == nil {
+		t.Fatalf("expected an error when the codec cannot move results aside")
+	}
+	if _, err := os.Stat(file); err != nil {
+		t.Fatalf("expected the file to be left in place")
+	}
+}
+
+func TestRegisterCodec(t *testing.T) {
+	codec := &memoryCodec{files: make(map[string]Results)}
+	RegisterCodec(".mem", codec)
+	defer RegisterCodec(".mem", nil)
+
+	if codecFor("results.mem") != Codec(codec) {
+		t.Fatalf("expected registered codec to be selected")
+	}
+	if _, ok := codecFor("results.gob").(gobCodec); !ok {
+		t.Fatalf("expected gob codec")
+	}
+	if _, ok := codecFor("results.journal").(journalCodec); !ok {
+		t.Fatalf("expected journal codec")
+	}
+	if _, ok := codecFor("results").(jsonCodec); !ok {
+		t.Fatalf("expected json fallback")
+	}
+
+	// The longest matching extension wins
+	RegisterCodec(".x.gob", codec)
+	defer RegisterCodec(".x.gob", nil)
+	if codecFor("results.x.gob") != Codec(codec) {
+		t.Fatalf("expected longest extension to win")
+	}
+}
+
+// removeResults removes a results file along with its backup and lock.
+func removeResults(file string) {
+	os.Remove(file)
+	os.Remove(file + backupSuffix)
+	os.Remove(file + lockSuffix)
+}
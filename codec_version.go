@@ -0,0 +1,201 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root
+
+package bench
+
+import (
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// formatVersion is the version of the envelope written around the results by
+// the JSON and gob codecs. Version 0 is the bare map written by older releases.
+const formatVersion = 1
+
+// ErrUnsupportedVersion is returned when a results file was written by a newer
+// release using a format version this release cannot read.
+var ErrUnsupportedVersion = errors.New("unsupported results file version")
+
+// migrations upgrade results decoded from version i to version i+1.
+var migrations = [formatVersion]func(Results) Results{
+	// 0 to 1: bare maps are wrapped in an envelope, the results are unchanged
+	// and become the latest results
+	func(results Results) Results { return results },
+}
+
+// jsonEnvelope is the versioned JSON representation of a results file.
+type jsonEnvelope struct {
+	Version   int                          `json:"version"`
+	Results   map[string]Result            `json:"results"`
+	Baselines map[string]map[string]Result `json:"baselines,omitempty"`
+}
+
+// gobEnvelope is the versioned gob representation of a results file. The
+// version is the first field so that newer envelopes can still be recognized.
+type gobEnvelope struct {
+	Version   int
+	Results   map[string]packedResult
+	Baselines map[string]map[string]packedResult
+}
+
+// migrate upgrades results decoded from the given format version to the current
+// one, failing for versions newer than this release supports.
+func migrate(version int, results Results) (Results, error) {
+	switch {
+	case version > formatVersion:
+		return nil, fmt.Errorf("%w %d, upgrade to read it (supported up to %d)", ErrUnsupportedVersion, version, formatVersion)
+	case version < 0:
+		return nil, fmt.Errorf("invalid results file version %d", version)
+	}
+
+	for ; version < formatVersion; version++ {
+		results = migrations[version](results)
+	}
+	return results, nil
+}
+
+// splitResults separates the latest results from the named baselines, which
+// are stored in their own field of the envelope.
+func splitResults(results Results) (latest map[string]Result, baselines map[string]map[string]Result) {
+	latest = results.get(LatestBaseline)
+	if latest == nil {
+		latest = make(map[string]Result)
+	}
+
+	for baseline, stored := range results {
+		if baseline != LatestBaseline && len(stored) > 0 {
+			if baselines == nil {
+				baselines = make(map[string]map[string]Result, len(results))
+			}
+			baselines[baseline] = stored
+		}
+	}
+	return
+}
+
+// joinResults combines the latest results with the named baselines.
+func joinResults(latest map[string]Result, baselines map[string]map[string]Result) Results {
+	results := make(Results, len(baselines)+1)
+	for baseline, stored := range baselines {
+		results[baseline] = stored
+	}
+	if len(latest) > 0 {
+		results[LatestBaseline] = latest
+	}
+	return results
+}
+
+// encodeJSON writes the results in a versioned JSON envelope.
+func encodeJSON(w io.Writer, results Results) error {
+	latest, baselines := splitResults(results)
+	data, err := json.MarshalIndent(jsonEnvelope{Version: formatVersion, Results: latest, Baselines: baselines}, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	_, err = w.Write(data)
+	return err
+}
+
+// decodeJSON reads a versioned JSON envelope, or a bare map of results written
+// before the envelope was introduced.
+func decodeJSON(r io.Reader) (Results, error) {
+	var fields map[string]json.RawMessage
+	if err := json.NewDecoder(r).Decode(&fields); err != nil {
+		return nil, err
+	}
+
+	// A bare map holds objects, while the envelope has a numeric version
+	var envelope jsonEnvelope
+	if err := json.Unmarshal(fields["version"], &envelope.Version); err == nil {
+		if err := json.Unmarshal(fields["results"], &envelope.Results); err != nil {
+			return nil, err
+		}
+		if data, ok := fields["baselines"]; ok {
+			if err := json.Unmarshal(data, &envelope.Baselines); err != nil {
+				return nil, err
+			}
+		}
+		return migrate(envelope.Version, joinResults(envelope.Results, envelope.Baselines))
+	}
+
+	results := make(map[string]Result, len(fields))
+	for name, field := range fields {
+		var result Result
+		if err := json.Unmarshal(field, &result); err != nil {
+			return nil, err
+		}
+		results[name] = result
+	}
+	return migrate(0, joinResults(results, nil))
+}
+
+// encodeGob writes the results with packed samples in a versioned gob envelope.
+func encodeGob(w io.Writer, results Results) error {
+	latest, baselines := splitResults(results)
+	envelope := gobEnvelope{Version: formatVersion, Results: packResults(latest)}
+	if len(baselines) > 0 {
+		envelope.Baselines = make(map[string]map[string]packedResult, len(baselines))
+		for baseline, stored := range baselines {
+			envelope.Baselines[baseline] = packResults(stored)
+		}
+	}
+
+	return gob.NewEncoder(w).Encode(envelope)
+}
+
+// decodeGob reads a versioned gob envelope, or a bare map of results written
+// before the envelope was introduced.
+func decodeGob(r io.Reader) (Results, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var envelope gobEnvelope
+	version := 0
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&envelope); err == nil {
+		version = envelope.Version
+	} else if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&envelope.Results); err != nil {
+		return nil, err
+	}
+
+	latest, err := unpackResults(envelope.Results)
+	if err != nil {
+		return nil, err
+	}
+
+	baselines := make(map[string]map[string]Result, len(envelope.Baselines))
+	for baseline, packed := range envelope.Baselines {
+		if baselines[baseline], err = unpackResults(packed); err != nil {
+			return nil, err
+		}
+	}
+	return migrate(version, joinResults(latest, baselines))
+}
+
+// packResults packs the samples of every result.
+func packResults(results map[string]Result) map[string]packedResult {
+	packed := make(map[string]packedResult, len(results))
+	for name, result := range results {
+		packed[name] = packResult(result)
+	}
+	return packed
+}
+
+// unpackResults unpacks the samples of every result.
+func unpackResults(packed map[string]packedResult) (map[string]Result, error) {
+	results := make(map[string]Result, len(packed))
+	for name, p := range packed {
+		result, err := p.unpack()
+		if err != nil {
+			return nil, err
+		}
+		results[name] = result
+	}
+	return results, nil
+}
@@ -0,0 +1,109 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root
+
+package bench
+
+import (
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJSONEnvelope(t *testing.T) {
+	file := "test_version.json"
+	defer removeResults(file)
+
+	results := Results{
+		LatestBaseline: {"bench": {Name: "bench", Samples: []float64{1, 2}}},
+		"main":         {"bench": {Name: "bench", Samples: []float64{3}}},
+	}
+	assert.NoError(t, jsonCodec{}.Save(file, results))
+
+	data, err := os.ReadFile(file)
+	assert.NoError(t, err)
+
+	var envelope jsonEnvelope
+	assert.NoError(t, json.Unmarshal(data, &envelope))
+	assert.Equal(t, formatVersion, envelope.Version)
+	assert.Equal(t, results[LatestBaseline], envelope.Results)
+	assert.Equal(t, results["main"], envelope.Baselines["main"])
+
+	loaded, err := jsonCodec{}.Load(file)
+	assert.NoError(t, err)
+	assert.Equal(t, results, loaded)
+}
+
+func TestJSONLegacy(t *testing.T) {
+	file := "test_version_legacy.json"
+	defer removeResults(file)
+
+	// A bare map may even hold a benchmark named "version", and names are kept as-is
+	legacy := `{"version": {"name": "version", "samples": [1]}, "bench": {"name": "bench", "samples": [2, 3]}, "@foo/bar": {"name": "@foo/bar"}}`
+	assert.NoError(t, os.WriteFile(file, []byte(legacy), 0644))
+
+	loaded, err := jsonCodec{}.Load(file)
+	assert.NoError(t, err)
+	assert.Len(t, loaded, 1)
+	assert.Equal(t, []float64{1}, loaded[LatestBaseline]["version"].Samples)
+	assert.Equal(t, []float64{2, 3}, loaded[LatestBaseline]["bench"].Samples)
+	assert.Equal(t, "@foo/bar", loaded[LatestBaseline]["@foo/bar"].Name)
+}
+
+func TestNewerVersion(t *testing.T) {
+	file := "test_version_newer.json"
+	defer removeResults(file)
+
+	// An older version is kept as backup, but must not be used instead
+	assert.NoError(t, jsonCodec{}.Save(file, Results{LatestBaseline: {"a": {Name: "a"}}}))
+	assert.NoError(t, jsonCodec{}.Save(file, Results{LatestBaseline: {"b": {Name: "b"}}}))
+	assert.NoError(t, os.WriteFile(file, []byte(`{"version": 99, "results": {}}`), 0644))
+
+	_, err := jsonCodec{}.Load(file)
+	assert.ErrorIs(t, err, ErrUnsupportedVersion)
+	assert.Contains(t, err.Error(), "version 99")
+}
+
+func TestGobEnvelope(t *testing.T) {
+	file := "test_version.gob"
+	defer removeResults(file)
+
+	var buffer bytes.Buffer
+	assert.NoError(t, gob.NewEncoder(&buffer).Encode(gobEnvelope{Version: formatVersion + 1}))
+	assert.NoError(t, os.WriteFile(file, buffer.Bytes(), 0644))
+
+	_, err := gobCodec{}.Load(file)
+	assert.ErrorIs(t, err, ErrUnsupportedVersion)
+
+	results := Results{
+		LatestBaseline: {"bench": {Name: "bench", Samples: []float64{1, 2}}},
+		"main":         {"bench": {Name: "bench", Samples: []float64{3}}},
+	}
+	assert.NoError(t, gobCodec{}.Save(file, results))
+	loaded, err := gobCodec{}.Load(file)
+	assert.NoError(t, err)
+	assert.Equal(t, results, loaded)
+}
+
+func TestJournalNewerVersion(t *testing.T) {
+	file := "test_version.journal"
+	defer removeResults(file)
+
+	assert.NoError(t, os.WriteFile(file, []byte("BENCHJ9\n"), 0644))
+	_, err := journalCodec{}.Load(file)
+	assert.ErrorIs(t, err, ErrUnsupportedVersion)
+	assert.ErrorIs(t, journalCodec{}.Append(file, LatestBaseline, []Result{{Name: "a"}}), ErrUnsupportedVersion)
+}
+
+func TestMigrate(t *testing.T) {
+	results := Results{LatestBaseline: {"a": {Name: "a"}, "@b": {Name: "@b"}, "@foo/bar": {Name: "@foo/bar"}}}
+	migrated, err := migrate(0, results)
+	assert.NoError(t, err)
+	assert.Equal(t, results, migrated)
+
+	_, err = migrate(-1, results)
+	assert.Error(t, err)
+}
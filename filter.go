@@ -0,0 +1,134 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root
+
+package bench
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// matcher selects benchmarks by name. Names and patterns are split on slashes
+// into elements, as with sub-benchmarks in "go test", and every element of a
+// pattern must match the corresponding element of the name.
+type matcher struct {
+	include []pattern // any must match, unless empty
+	exclude []pattern // none may match
+}
+
+// pattern matches the leading elements of a benchmark name.
+type pattern []func(string) bool
+
+// newMatcher compiles the regular expression and glob include patterns along
+// with the regular expression exclude patterns of the configuration.
+func newMatcher(c *config) (*matcher, error) {
+	m := new(matcher)
+	for _, expr := range c.match {
+		p, err := compileRegexp(expr)
+		if err != nil {
+			return nil, err
+		}
+		m.include = append(m.include, p)
+	}
+
+	for _, glob := range c.glob {
+		p, err := compileGlob(glob)
+		if err != nil {
+			return nil, err
+		}
+		m.include = append(m.include, p)
+	}
+
+	for _, expr := range c.skip {
+		p, err := compileRegexp(expr)
+		if err != nil {
+			return nil, err
+		}
+		m.exclude = append(m.exclude, p)
+	}
+	return m, nil
+}
+
+// match reports whether the benchmark name is selected.
+func (m *matcher) match(name string) bool {
+	elems := strings.Split(name, "/")
+	for _, p := range m.exclude {
+		if p.match(elems) {
+			return false
+		}
+	}
+
+	for _, p := range m.include {
+		if p.match(elems) {
+			return true
+		}
+	}
+	return len(m.include) == 0
+}
+
+// match reports whether every element of the pattern matches the name.
+func (p pattern) match(elems []string) bool {
+	if len(elems) < len(p) {
+		return false
+	}
+
+	for i, fn := range p {
+		if !fn(elems[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// compileRegexp compiles an unanchored regular expression per element.
+func compileRegexp(expr string) (pattern, error) {
+	var p pattern
+	for _, elem := range splitPattern(expr) {
+		re, err := regexp.Compile(elem)
+		if err != nil {
+			return nil, fmt.Errorf("bench: invalid filter %q: %w", expr, err)
+		}
+		p = append(p, re.MatchString)
+	}
+	return p, nil
+}
+
+// compileGlob compiles a glob pattern, matched against whole elements.
+func compileGlob(glob string) (pattern, error) {
+	var p pattern
+	for _, elem := range strings.Split(glob, "/") {
+		if _, err := path.Match(elem, ""); err != nil {
+			return nil, fmt.Errorf("bench: invalid filter %q: %w", glob, err)
+		}
+		p = append(p, func(s string) bool {
+			matched, _ := path.Match(elem, s)
+			return matched
+		})
+	}
+	return p, nil
+}
+
+// splitPattern splits a regular expression on the slashes which are not
+// escaped nor within brackets or parentheses.
+func splitPattern(expr string) []string {
+	var out []string
+	depth, start := 0, 0
+	for i := 0; i < len(expr); i++ {
+		switch expr[i] {
+		case '\\':
+			i++
+		case '[', '(':
+			depth++
+		case ']', ')':
+			depth--
+		case '/':
+			if depth == 0 {
+				out = append(out, expr[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(out, expr[start:])
+}
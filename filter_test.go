@@ -0,0 +1,63 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root
+
+package bench
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMatcher(t *testing.T) {
+	tests := []struct {
+		config config
+		name   string
+		want   bool
+	}{
+		{config{}, "anything", true},
+		{config{match: []string{"find"}}, "prefind", true},
+		{config{match: []string{"^find$"}}, "find/small", true},
+		{config{match: []string{"^find$"}}, "finder", false},
+		{config{match: []string{"find/small"}}, "find/small/x", true},
+		{config{match: []string{"find/small"}}, "find/large", false},
+		{config{match: []string{"find/small"}}, "find", false},
+		{config{match: []string{"find/(a|b)"}}, "find/b/c", true},
+		{config{match: []string{"sort", "find"}}, "find", true},
+		{config{match: []string{"sort", "find"}}, "map", false},
+		{config{glob: []string{"find/*"}}, "find/small", true},
+		{config{glob: []string{"f*"}}, "find/small", true},
+		{config{glob: []string{"f*d"}}, "find", true},
+		{config{glob: []string{"f*"}}, "sort", false},
+		{config{skip: []string{"large"}}, "find/large", true},
+		{config{skip: []string{"/large"}}, "find/large", false},
+		{config{skip: []string{"/large"}}, "large/small", true},
+		{config{match: []string{"find"}, skip: []string{"find/large"}}, "find/large", false},
+		{config{match: []string{"find"}, skip: []string{"find/large"}}, "find/small", true},
+	}
+
+	for _, tc := range tests {
+		m, err := newMatcher(&tc.config)
+		assert.NoError(t, err)
+		assert.Equal(t, tc.want, m.match(tc.name), "%+v %s", tc.config, tc.name)
+	}
+}
+
+func TestMatcherInvalid(t *testing.T) {
+	_, err := newMatcher(&config{match: []string{"("}})
+	assert.Error(t, err)
+	_, err = newMatcher(&config{glob: []string{"["}})
+	assert.Error(t, err)
+	_, err = newMatcher(&config{skip: []string{"a/("}})
+	assert.Error(t, err)
+
+	b := &B{config: config{match: []string{"("}}}
+	assert.False(t, b.shouldRun("anything"))
+	assert.Error(t, b.open())
+}
+
+func TestSplitPattern(t *testing.T) {
+	assert.Equal(t, []string{"a", "b"}, splitPattern("a/b"))
+	assert.Equal(t, []string{"a", "[/]", `b\/c`}, splitPattern(`a/[/]/b\/c`))
+	assert.Equal(t, []string{"(a/b)", ""}, splitPattern("(a/b)/"))
+}
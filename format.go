@@ -15,10 +15,20 @@ const (
 	allocSame
 	allocBetter
 	allocWorse
+	allocUncertain
 )
 
-// formatComparison formats statistical comparison between two sample sets using BCa bootstrap
+// formatComparison formats statistical comparison between two sample sets using BCa bootstrap,
+// marking comparisons of drifting samples with a tilde.
 func (r *B) formatComparison(report Report) string {
+	if report.Unstable {
+		return r.formatVerdict(report) + " ~"
+	}
+	return r.formatVerdict(report)
+}
+
+// formatVerdict formats the verdict and change of a comparison.
+func (r *B) formatVerdict(report Report) string {
 	ratio := report.Ratio
 	if ratio == 0 && report.MedianControl > 0 && report.MedianVariant > 0 {
 		ratio = report.MedianVariant / report.MedianControl
@@ -36,8 +46,10 @@ func (r *B) formatComparison(report Report) string {
 	change := ratioToChange(ratio)
 
 	switch {
-	case !report.Significant:
+	case !report.Significant && report.Verdict == VerdictEquivalent:
 		return "🟰 similar"
+	case !report.Significant:
+		return "❔ inconclusive"
 	case ratio < 1:
 		return fmt.Sprintf("✅ %s", formatChange(change))
 	default:
@@ -78,6 +90,19 @@ func formatTime(nsPerOp float64) string {
 	}
 }
 
+// formatTimeWithWarnings formats nanoseconds per operation, marking results with
+// too many outliers with a warning and drifting results with a tilde.
+func formatTimeWithWarnings(nsPerOp float64, result Result) string {
+	value := formatTime(nsPerOp)
+	if hasOutlierWarning(result.Outliers, len(result.Samples)) {
+		value += " ⚠"
+	}
+	if result.Unstable {
+		value += " ~"
+	}
+	return value
+}
+
 // formatOps formats operations per second
 func formatOps(opsPerSec float64) string {
 	if opsPerSec >= 1000000 {
@@ -101,8 +126,29 @@ func formatAllocs(allocsPerOp float64) string {
 	}
 }
 
+// formatBytes formats number of bytes allocated per operation
+func formatBytes(bytesPerOp float64) string {
+	switch {
+	case bytesPerOp >= 1<<20:
+		return fmt.Sprintf("%.1f MB", bytesPerOp/(1<<20))
+	case bytesPerOp >= 1<<10:
+		return fmt.Sprintf("%.1f KB", bytesPerOp/(1<<10))
+	case bytesPerOp >= 1:
+		return fmt.Sprintf("%.0f B", bytesPerOp)
+	default:
+		return "0 B"
+	}
+}
+
 func formatAllocsWithChange(allocsPerOp float64, change allocChange) string {
-	value := formatAllocs(allocsPerOp)
+	return formatWithChange(formatAllocs(allocsPerOp), change)
+}
+
+func formatBytesWithChange(bytesPerOp float64, change allocChange) string {
+	return formatWithChange(formatBytes(bytesPerOp), change)
+}
+
+func formatWithChange(value string, change allocChange) string {
 	switch change {
 	case allocBetter:
 		return "✅ " + value
@@ -110,6 +156,8 @@ func formatAllocsWithChange(allocsPerOp float64, change allocChange) string {
 		return "❌ " + value
 	case allocSame:
 		return "🟰 " + value
+	case allocUncertain:
+		return "❔ " + value
 	default:
 		return value
 	}
@@ -122,13 +170,32 @@ func allocIntValue(allocsPerOp float64) int64 {
 	return int64(math.Round(allocsPerOp))
 }
 
-func compareAllocs(previous, current []float64) allocChange {
+// compareAllocs compares allocation samples with the configured inference, as
+// for timings, but against the allocation threshold. Counts that round to zero,
+// or whose bootstrap does not vary, are classified by their rounded medians.
+func (r *B) compareAllocs(previous, current []float64) (*Report, allocChange) {
 	if len(previous) == 0 || len(current) == 0 {
-		return allocUnknown
+		return nil, allocUnknown
 	}
 
+	report := r.compareWith(previous, current, r.allocThreshold)
 	prev := allocIntValue(median(previous))
 	curr := allocIntValue(median(current))
+	switch {
+	case prev == 0 || curr == 0 || report.Degenerate:
+		return &report, roundedAllocChange(prev, curr)
+	case report.Verdict == VerdictChanged && report.Delta < 0:
+		return &report, allocBetter
+	case report.Verdict == VerdictChanged:
+		return &report, allocWorse
+	case report.Verdict == VerdictEquivalent:
+		return &report, allocSame
+	default:
+		return &report, allocUncertain
+	}
+}
+
+func roundedAllocChange(prev, curr int64) allocChange {
 	switch {
 	case curr == prev:
 		return allocSame
@@ -17,6 +17,12 @@ func TestFormatHelpers(t *testing.T) {
 	assert.Equal(t, "❌ 1", formatAllocsWithChange(1, allocWorse))
 	assert.Equal(t, "🟰 0", formatAllocsWithChange(0, allocSame))
 	assert.Equal(t, "0", formatAllocsWithChange(0, allocUnknown))
+	assert.Equal(t, "❔ 3", formatAllocsWithChange(3, allocUncertain))
+	assert.Equal(t, "0 B", formatBytes(0.5))
+	assert.Equal(t, "512 B", formatBytes(512))
+	assert.Equal(t, "1.5 KB", formatBytes(1536))
+	assert.Equal(t, "2.0 MB", formatBytes(2<<20))
+	assert.Equal(t, "✅ 1.5 KB", formatBytesWithChange(1536, allocBetter))
 
 	assert.Contains(t, formatTime(2e6), "ms")
 	assert.Contains(t, formatTime(2e3), "µs")
@@ -57,14 +63,73 @@ func TestFormatComparisonCases(t *testing.T) {
 	out := b.formatComparison(r)
 	assert.Equal(t, "✅ +100%", out)
 	assert.NotContains(t, out, "[")
+
+	// Non-significant results distinguish equivalence from inconclusive intervals
+	r = Report{MedianControl: 100, MedianVariant: 101, Verdict: VerdictEquivalent}
+	assert.Equal(t, "🟰 similar", b.formatComparison(r))
+	r = Report{MedianControl: 100, MedianVariant: 101, Verdict: VerdictInconclusive}
+	assert.Equal(t, "❔ inconclusive", b.formatComparison(r))
+
+	// Drifting samples are marked
+	r = Report{MedianControl: 100, MedianVariant: 200, Ratio: 2, Significant: true, Unstable: true}
+	assert.Equal(t, "❌ -50% ~", b.formatComparison(r))
 }
 
 func TestCompareAllocs(t *testing.T) {
-	assert.Equal(t, allocUnknown, compareAllocs(nil, []float64{1}))
-	assert.Equal(t, allocSame, compareAllocs([]float64{1, 1, 1}, []float64{1, 1, 1}))
-	assert.Equal(t, allocBetter, compareAllocs([]float64{2, 2, 2}, []float64{1, 1, 1}))
-	assert.Equal(t, allocWorse, compareAllocs([]float64{1, 1, 1}, []float64{2, 2, 2}))
+	b := &B{config: defaultConfig()}
+	b.bootstrap = 1000
+
+	report, change := b.compareAllocs(nil, []float64{1})
+	assert.Nil(t, report)
+	assert.Equal(t, allocUnknown, change)
+	assert.Equal(t, allocSame, allocChangeOf(b, []float64{1, 1, 1}, []float64{1, 1, 1}))
+	assert.Equal(t, allocBetter, allocChangeOf(b, []float64{2, 2, 2}, []float64{1, 1, 1}))
+	assert.Equal(t, allocWorse, allocChangeOf(b, []float64{1, 1, 1}, []float64{2, 2, 2}))
+	assert.Equal(t, allocWorse, allocChangeOf(b, []float64{0, 0, 0}, []float64{2, 2, 2}))
 
 	// Float medians can differ while the displayed alloc count stays the same.
-	assert.Equal(t, allocSame, compareAllocs([]float64{35.8, 36.2}, []float64{36.1, 35.9}))
+	assert.Equal(t, allocSame, allocChangeOf(b, []float64{35.8, 36.2}, []float64{36.1, 35.9}))
+}
+
+func TestCompareAllocsWithVariance(t *testing.T) {
+	b := &B{config: defaultConfig()}
+	b.bootstrap = 1000
+	b.confidence = 95
+
+	// Amortized growth makes allocation counts vary between samples
+	prev := []float64{10, 12, 10, 11, 10, 12, 11, 10, 12, 10}
+	assert.Equal(t, allocBetter, allocChangeOf(b, prev, []float64{5, 6, 5, 5, 6, 5, 6, 5, 5, 6}))
+	assert.Equal(t, allocWorse, allocChangeOf(b, prev, []float64{20, 22, 21, 20, 22, 21, 20, 22, 21, 20}))
+	assert.Equal(t, allocUncertain, allocChangeOf(b, prev, []float64{8, 14, 9, 13, 10, 12, 8, 14, 9, 13}))
+
+	// A separate threshold applies to allocations
+	b.allocThreshold = 150
+	assert.Equal(t, allocSame, allocChangeOf(b, prev, []float64{5, 6, 5, 5, 6, 5, 6, 5, 5, 6}))
+}
+
+func TestCompareAllocsReport(t *testing.T) {
+	b := &B{config: defaultConfig()}
+	b.bootstrap = 1000
+	b.confidence = 95
+
+	// Allocations are compared through the bootstrap, like timings
+	prev := []float64{10, 12, 10, 11, 10, 12, 11, 10, 12, 10}
+	report, change := b.compareAllocs(prev, []float64{20, 22, 21, 20, 22, 21, 20, 22, 21, 20})
+	assert.Equal(t, allocWorse, change)
+	assert.True(t, report.Significant)
+	assert.Equal(t, VerdictChanged, report.Verdict)
+	assert.Equal(t, 1000, report.Samples)
+	assert.Less(t, report.RatioCI[0], report.Ratio)
+	assert.Greater(t, report.RatioCI[1], report.Ratio)
+
+	// Constant counts give a degenerate bootstrap and are compared by their medians
+	report, change = b.compareAllocs([]float64{3, 3, 3, 3}, []float64{4, 4, 4, 4})
+	assert.Equal(t, allocWorse, change)
+	assert.True(t, report.Degenerate)
+}
+
+// allocChangeOf returns the classification of an allocation comparison.
+func allocChangeOf(b *B, previous, current []float64) allocChange {
+	_, change := b.compareAllocs(previous, current)
+	return change
 }
@@ -0,0 +1,38 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root
+
+package bench
+
+import "os"
+
+// lockSuffix is appended to the results filename for the advisory lock file. A
+// separate file is used because atomic writes replace the results file itself.
+const lockSuffix = ".lock"
+
+// lockResults acquires an exclusive advisory lock guarding the results file
+// across processes and returns a function which releases it.
+func lockResults(filename string) (func(), error) {
+	f, err := os.OpenFile(filename+lockSuffix, os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := lockFile(f); err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	return func() {
+		unlockFile(f)
+		f.Close()
+	}, nil
+}
+
+// lockCodec locks the results file through the codec when it implements Locker,
+// since codecs which are not backed by files have nothing to lock.
+func lockCodec(codec Codec, filename string) (func(), error) {
+	if locker, ok := codec.(Locker); ok {
+		return locker.Lock(filename)
+	}
+	return func() {}, nil
+}
@@ -0,0 +1,20 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root
+
+//go:build !(linux || darwin || freebsd || netbsd || openbsd || dragonfly)
+
+package bench
+
+import "os"
+
+// lockFile is a no-op on platforms without flock, such as Windows, Solaris or
+// AIX, where concurrent processes writing to the same results file are not
+// coordinated.
+func lockFile(f *os.File) error {
+	return nil
+}
+
+// unlockFile is a no-op on platforms without flock.
+func unlockFile(f *os.File) error {
+	return nil
+}
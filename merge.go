@@ -0,0 +1,166 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root
+
+package bench
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"time"
+)
+
+// MergePolicy selects how conflicting results are resolved when merging files.
+type MergePolicy int
+
+const (
+	// MergeNewest keeps the result with the most recent timestamp.
+	MergeNewest MergePolicy = iota
+
+	// MergeHistory keeps the most recent result and moves the older one into a
+	// "history-<timestamp>" baseline, named after the run time of the older result.
+	// When that baseline already holds another result for the benchmark, a
+	// "-2", "-3", ... suffix is added so that no result is lost.
+	MergeHistory
+
+	// MergeFail returns an error and leaves the destination file untouched.
+	MergeFail
+)
+
+// Merge merges the results of the source files into the results file configured
+// by the options, resolving results of a baseline stored under the same name with
+// the given policy. Sources are decoded with the configured codec, or otherwise
+// with the codec registered for their extension.
+func Merge(sources []string, policy MergePolicy, opts ...Option) error {
+	cfg := configure(opts)
+	loaded := make([]Results, 0, len(sources))
+	for _, source := range sources {
+		codec := cfg.codec
+		if !cfg.customCodec {
+			codec = codecFor(source)
+		}
+
+		results, err := codec.Load(source)
+		if err != nil {
+			return err
+		}
+		loaded = append(loaded, results)
+	}
+
+	return updateResults(cfg, func(results Results) error {
+		for _, source := range loaded {
+			if err := mergeResults(results, source, policy); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// mergeResults merges the source results into the destination.
+func mergeResults(dst, src Results, policy MergePolicy) error {
+	for baseline, stored := range src {
+		for name, result := range stored {
+			current, exists := dst[baseline][name]
+			switch {
+			case !exists:
+				dst.put(baseline, result)
+				continue
+			case reflect.DeepEqual(current, result):
+				continue
+			case policy == MergeFail:
+				return fmt.Errorf("bench: conflicting results for %q in baseline %q", name, baseline)
+			}
+
+			older := result
+			if result.Timestamp > current.Timestamp {
+				older = current
+				dst.put(baseline, result)
+			}
+
+			if policy == MergeHistory {
+				dst.put(historyBaseline(dst, older), older)
+			}
+		}
+	}
+	return nil
+}
+
+// historyBaseline returns the history baseline in which the older result can be
+// kept without replacing a different result of the same benchmark.
+func historyBaseline(dst Results, older Result) string {
+	name := "history-" + strconv.FormatInt(older.Timestamp, 10)
+	for i := 2; ; i++ {
+		current, exists := dst[name][older.Name]
+		if !exists || reflect.DeepEqual(current, older) {
+			return name
+		}
+		name = fmt.Sprintf("history-%d-%d", older.Timestamp, i)
+	}
+}
+
+// Prune removes the results of every baseline which were recorded more than the
+// given age ago, from the results file configured by the options. Benchmarks are
+// selected as in a run, with WithFilter, WithMatch, WithGlob and WithSkip, while
+// a zero age matches every result. It returns the number of results removed.
+func Prune(age time.Duration, opts ...Option) (removed int, err error) {
+	cfg := configure(opts)
+	if age <= 0 && cfg.filter == "" && len(cfg.match)+len(cfg.glob)+len(cfg.skip) == 0 {
+		return 0, errors.New("bench: prune requires a filter or an age")
+	}
+
+	m, err := newMatcher(&cfg)
+	if err != nil {
+		return 0, err
+	}
+
+	selector := &B{config: cfg, matcher: m}
+	cutoff := time.Now().Add(-age).Unix()
+	err = updateResults(cfg, func(results Results) error {
+		for baseline, stored := range results {
+			for name, result := range stored {
+				if !selector.shouldRun(name) {
+					continue
+				}
+				if age > 0 && result.Timestamp >= cutoff {
+					continue
+				}
+
+				delete(stored, name)
+				removed++
+			}
+			if len(stored) == 0 {
+				delete(results, baseline)
+			}
+		}
+		return nil
+	})
+	return
+}
+
+// Rename renames a benchmark in every baseline of the results file configured by
+// the options, so that its history is preserved under the new name.
+func Rename(from, to string, opts ...Option) error {
+	return updateResults(configure(opts), func(results Results) error {
+		renamed := 0
+		for baseline, stored := range results {
+			result, ok := stored[from]
+			if !ok {
+				continue
+			}
+			if _, exists := stored[to]; exists {
+				return fmt.Errorf("bench: benchmark %q already exists in baseline %q", to, baseline)
+			}
+
+			delete(stored, from)
+			result.Name = to
+			stored[to] = result
+			renamed++
+		}
+		if renamed == 0 {
+			return fmt.Errorf("bench: benchmark %q not found", from)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,174 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root
+
+package bench
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMerge(t *testing.T) {
+	dst, shard := "test_merge.json", "test_merge_shard.gob"
+	defer removeResults(dst)
+	defer removeResults(shard)
+
+	reset := func() {
+		assert.NoError(t, jsonCodec{}.Save(dst, Results{LatestBaseline: {
+			"a": {Name: "a", Samples: []float64{1}, Timestamp: 10},
+			"b": {Name: "b", Samples: []float64{2}, Timestamp: 30},
+		}}))
+		assert.NoError(t, gobCodec{}.Save(shard, Results{LatestBaseline: {
+			"a": {Name: "a", Samples: []float64{3}, Timestamp: 20},
+			"b": {Name: "b", Samples: []float64{2}, Timestamp: 30},
+			"c": {Name: "c", Samples: []float64{4}, Timestamp: 20},
+		}}))
+	}
+
+	// Newest wins, identical results do not conflict
+	reset()
+	assert.NoError(t, Merge([]string{shard}, MergeNewest, WithFile(dst)))
+	loaded, err := jsonCodec{}.Load(dst)
+	assert.NoError(t, err)
+	assert.Len(t, loaded[LatestBaseline], 3)
+	assert.Equal(t, []float64{3}, loaded[LatestBaseline]["a"].Samples)
+
+	// Older results are kept in a history baseline
+	reset()
+	assert.NoError(t, Merge([]string{shard}, MergeHistory, WithFile(dst)))
+	loaded, err = jsonCodec{}.Load(dst)
+	assert.NoError(t, err)
+	assert.Len(t, loaded[LatestBaseline], 3)
+	assert.Equal(t, []float64{3}, loaded[LatestBaseline]["a"].Samples)
+	assert.Equal(t, []float64{1}, loaded["history-10"]["a"].Samples)
+	assert.Equal(t, "a", loaded["history-10"]["a"].Name)
+
+	// Conflicts fail without touching the destination
+	reset()
+	assert.Error(t, Merge([]string{shard}, MergeFail, WithFile(dst)))
+	loaded, err = jsonCodec{}.Load(dst)
+	assert.NoError(t, err)
+	assert.Len(t, loaded[LatestBaseline], 2)
+
+	// Sources which cannot be decoded are not merged
+	bad := "test_merge_bad.json"
+	defer removeResults(bad)
+	assert.NoError(t, os.WriteFile(bad, []byte("bad"), 0644))
+	assert.Error(t, Merge([]string{bad}, MergeNewest, WithFile(dst)))
+}
+
+func TestMergeWithCodec(t *testing.T) {
+	codec := &memoryCodec{files: make(map[string]Results)}
+	codec.files["dst"] = Results{LatestBaseline: {"a": {Name: "a", Timestamp: 1}}}
+	codec.files["src"] = Results{"main": {"a": {Name: "a", Timestamp: 2}}}
+
+	// Sources are decoded with the configured codec rather than by extension
+	assert.NoError(t, Merge([]string{"src"}, MergeFail, WithFile("dst"), WithCodec(codec)))
+	assert.Equal(t, int64(1), codec.files["dst"][LatestBaseline]["a"].Timestamp)
+	assert.Equal(t, int64(2), codec.files["dst"]["main"]["a"].Timestamp)
+}
+
+func TestPrune(t *testing.T) {
+	file := "test_prune.json"
+	defer removeResults(file)
+
+	now := time.Now().Unix()
+	assert.NoError(t, jsonCodec{}.Save(file, Results{
+		LatestBaseline: {
+			"old/a": {Name: "old/a", Timestamp: now},
+			"new/a": {Name: "new/a", Timestamp: now},
+			"new/b": {Name: "new/b", Timestamp: now - 3600},
+		},
+		"main": {"old/a": {Name: "old/a", Timestamp: now}},
+	}))
+
+	opt := WithFile(file)
+	removed, err := Prune(0, opt, WithGlob("old"))
+	assert.NoError(t, err)
+	assert.Equal(t, 2, removed)
+
+	removed, err = Prune(time.Minute, opt)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, removed)
+
+	loaded, err := jsonCodec{}.Load(file)
+	assert.NoError(t, err)
+	assert.Len(t, loaded, 1)
+	assert.Len(t, loaded[LatestBaseline], 1)
+	assert.Contains(t, loaded[LatestBaseline], "new/a")
+
+	_, err = Prune(0, opt)
+	assert.Error(t, err)
+	_, err = Prune(0, opt, WithMatch("("))
+	assert.Error(t, err)
+}
+
+func TestPruneMatchesNestedNames(t *testing.T) {
+	file := "test_prune_nested.json"
+	defer removeResults(file)
+
+	assert.NoError(t, jsonCodec{}.Save(file, Results{LatestBaseline: {
+		"legacy/a":    {Name: "legacy/a"},
+		"legacy/a/b":  {Name: "legacy/a/b"},
+		"legacy/keep": {Name: "legacy/keep"},
+		"current/a":   {Name: "current/a"},
+	}}))
+
+	// Patterns match the leading elements of the name, as with -bench
+	removed, err := Prune(0, WithFile(file), WithGlob("legacy/*"), WithSkip("legacy/keep"))
+	assert.NoError(t, err)
+	assert.Equal(t, 2, removed)
+
+	loaded, err := jsonCodec{}.Load(file)
+	assert.NoError(t, err)
+	assert.Len(t, loaded[LatestBaseline], 2)
+	assert.Contains(t, loaded[LatestBaseline], "legacy/keep")
+	assert.Contains(t, loaded[LatestBaseline], "current/a")
+}
+
+func TestMergeHistoryCollision(t *testing.T) {
+	dst := Results{LatestBaseline: {"a": {Name: "a", Samples: []float64{3}, Timestamp: 20}}}
+
+	// Two shards whose older results share a timestamp keep both
+	for _, samples := range []float64{1, 2} {
+		src := Results{LatestBaseline: {"a": {Name: "a", Samples: []float64{samples}, Timestamp: 10}}}
+		assert.NoError(t, mergeResults(dst, src, MergeHistory))
+	}
+
+	assert.Equal(t, []float64{3}, dst[LatestBaseline]["a"].Samples)
+	assert.Equal(t, []float64{1}, dst["history-10"]["a"].Samples)
+	assert.Equal(t, []float64{2}, dst["history-10-2"]["a"].Samples)
+
+	// Merging the same result again does not add another copy
+	src := Results{LatestBaseline: {"a": {Name: "a", Samples: []float64{2}, Timestamp: 10}}}
+	assert.NoError(t, mergeResults(dst, src, MergeHistory))
+	assert.Len(t, dst, 3)
+}
+
+func TestRename(t *testing.T) {
+	file := "test_rename.json"
+	defer removeResults(file)
+
+	assert.NoError(t, jsonCodec{}.Save(file, Results{
+		LatestBaseline: {
+			"a": {Name: "a", Samples: []float64{1}},
+			"b": {Name: "b"},
+		},
+		"main": {"a": {Name: "a", Samples: []float64{2}}},
+	}))
+
+	opt := WithFile(file)
+	assert.NoError(t, Rename("a", "c", opt))
+	loaded, err := jsonCodec{}.Load(file)
+	assert.NoError(t, err)
+	assert.Equal(t, "c", loaded[LatestBaseline]["c"].Name)
+	assert.Equal(t, "c", loaded["main"]["c"].Name)
+	assert.Equal(t, []float64{2}, loaded["main"]["c"].Samples)
+	assert.NotContains(t, loaded[LatestBaseline], "a")
+
+	assert.Error(t, Rename("a", "d", opt))
+	assert.Error(t, Rename("c", "b", opt))
+}
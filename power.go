@@ -0,0 +1,173 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root
+
+package bench
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"gonum.org/v1/gonum/stat/distuv"
+)
+
+const (
+	// defaultPower is the probability of detecting a true change of the target size
+	defaultPower = 0.8
+
+	// medianEfficiency is the asymptotic variance inflation of the median relative
+	// to the mean under normality (pi/2).
+	medianEfficiency = math.Pi / 2
+)
+
+// Power describes how sensitive a benchmark is, given the variability of its samples.
+type Power struct {
+	Name      string        // Name is the benchmark name
+	Samples   int           // Samples is the number of stored samples
+	Noise     float64       // Noise is the robust standard deviation of the samples, in percent
+	MinEffect float64       // MinEffect is the minimum detectable change, in percent
+	Required  int           // Required is the number of samples needed to detect the threshold
+	Duration  time.Duration // Duration is the sample duration that detects the threshold with the current samples
+	Unknown   bool          // Unknown is set when the samples are too few or not strictly positive to be analyzed
+}
+
+// Sufficient reports whether the current sample count can detect the threshold
+// change. It is false when the power could not be estimated.
+func (p Power) Sufficient() bool {
+	return !p.Unknown && p.Required <= p.Samples
+}
+
+// Analyze estimates the statistical power of every stored benchmark in the
+// configured results file, sorted by name.
+func Analyze(opts ...Option) ([]Power, error) {
+	runner := &B{config: configure(opts)}
+	return runner.analyze()
+}
+
+// analyze estimates the power of the stored benchmarks of the compared baseline
+// matching the filter.
+func (r *B) analyze() ([]Power, error) {
+	results, err := r.loadResults()
+	if err != nil {
+		return nil, err
+	}
+
+	confidence, _ := r.adjustedConfidence()
+	stored := results.get(r.baseline)
+	out := make([]Power, 0, len(stored))
+	for name, result := range stored {
+		if !r.shouldRun(name) || len(result.Samples) < minSamples {
+			continue
+		}
+
+		p := estimatePower(result.Samples, confidence, r.threshold, r.duration)
+		p.Name = name
+		out = append(out, p)
+	}
+
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].Name < out[j].Name
+	})
+	return out, nil
+}
+
+// estimatePower estimates the minimum detectable effect and the required number
+// of samples for a two-sample comparison of medians in log space, assuming both
+// runs share the noise observed in the samples.
+func estimatePower(samples []float64, confidence, minChangePercent float64, duration time.Duration) Power {
+	logs, ok := logSamples(samples)
+	if !ok || len(samples) < minSamples {
+		return Power{Samples: len(samples), Unknown: true}
+	}
+
+	n := float64(len(samples))
+	sigma := robustSigma(logs)
+	z := distuv.UnitNormal.Quantile(1-(1-normalizeConfidence(confidence))/2) + distuv.UnitNormal.Quantile(defaultPower)
+
+	// Variance of the difference between two medians of n samples each
+	variance := 2 * medianEfficiency * sigma * sigma
+	minEffect := z * math.Sqrt(variance/n)
+
+	power := Power{
+		Samples:   len(samples),
+		Noise:     math.Expm1(sigma) * 100,
+		MinEffect: math.Expm1(minEffect) * 100,
+		Required:  len(samples),
+		Duration:  duration,
+	}
+
+	target := math.Log1p(math.Max(0, minChangePercent) / 100)
+	if target <= 0 || sigma == 0 {
+		return power
+	}
+
+	required := math.Ceil(variance * z * z / (target * target))
+	power.Required = max(minSamples, int(math.Min(required, math.MaxInt32)))
+
+	// Per-sample noise shrinks roughly with the square root of the sample duration,
+	// so the same sensitivity can be reached by sampling longer instead of more.
+	if power.Required > power.Samples {
+		scale := float64(power.Required) / n
+		power.Duration = time.Duration(math.Min(float64(duration)*scale, math.MaxInt64))
+	}
+	return power
+}
+
+// robustSigma estimates the standard deviation from the median absolute deviation.
+func robustSigma(data []float64) float64 {
+	center := median(data)
+	deviations := make([]float64, len(data))
+	for i, v := range data {
+		deviations[i] = math.Abs(v - center)
+	}
+
+	return 1.4826 * medianInPlace(deviations)
+}
+
+// printPower prints the power analysis table for the stored benchmarks,
+// separated from the results by an empty line.
+func (r *B) printPower() {
+	powers, err := r.analyze()
+	if err != nil {
+		fmt.Printf("Error reading results file: %v\n", err)
+		return
+	}
+
+	if r.format == FormatJSON {
+		for _, p := range powers {
+			r.printJSON(newPowerRecord(p))
+		}
+		return
+	}
+
+	w := r.writer()
+	fmt.Fprintln(w)
+	fmt.Fprintf(w, "%-20s %-12s %-12s %-12s %-12s %-18s\n", "name", "samples", "noise", "detects", "needs", "suggest")
+	fmt.Fprintf(w, "%-20s %-12s %-12s %-12s %-12s %-18s\n", "--------------------", "------------", "------------", "------------", "------------", "------------------")
+	for _, p := range powers {
+		if p.Unknown {
+			fmt.Fprintf(w, "%-20s %-12d %-12s %-12s %-12s %-18s\n", p.Name, p.Samples, "n/a", "n/a", "n/a", formatSuggestion(p))
+			continue
+		}
+
+		fmt.Fprintf(w, "%-20s %-12d %-12s %-12s %-12d %-18s\n", p.Name,
+			p.Samples,
+			fmt.Sprintf("±%.1f%%", p.Noise),
+			fmt.Sprintf("±%.1f%%", p.MinEffect),
+			p.Required,
+			formatSuggestion(p))
+	}
+}
+
+// formatSuggestion formats the options needed to detect the threshold change.
+func formatSuggestion(p Power) string {
+	switch {
+	case p.Unknown:
+		return "unknown"
+	case p.Sufficient():
+		return "ok"
+	}
+
+	return fmt.Sprintf("n=%d or d=%s", p.Required, p.Duration.Round(time.Millisecond))
+}
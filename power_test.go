@@ -0,0 +1,81 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root
+
+package bench
+
+import (
+	"math"
+	"math/rand/v2"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEstimatePower(t *testing.T) {
+	t.Parallel()
+
+	rng := rand.New(rand.NewPCG(1, 2))
+	samples := make([]float64, 100)
+	for i := range samples {
+		samples[i] = 100 * math.Exp(0.05*rng.NormFloat64())
+	}
+
+	p := estimatePower(samples, 0.95, 5, 10*time.Millisecond)
+	assert.Equal(t, 100, p.Samples)
+	assert.InDelta(t, 5, p.Noise, 1.5)
+	assert.True(t, p.MinEffect > 0 && p.MinEffect < 5, "100 samples should detect a 5%% change")
+	assert.True(t, p.Sufficient())
+
+	// A smaller threshold needs more samples or longer sampling
+	p = estimatePower(samples, 0.95, 1, 10*time.Millisecond)
+	assert.False(t, p.Sufficient())
+	assert.Greater(t, p.Required, 100)
+	assert.Greater(t, p.Duration, 10*time.Millisecond)
+	assert.Contains(t, formatSuggestion(p), "n=")
+}
+
+func TestEstimatePowerConstant(t *testing.T) {
+	t.Parallel()
+
+	p := estimatePower([]float64{10, 10, 10, 10}, 0.95, 5, time.Millisecond)
+	assert.Equal(t, 0.0, p.Noise)
+	assert.Equal(t, 0.0, p.MinEffect)
+	assert.Equal(t, 4, p.Required)
+	assert.Equal(t, "ok", formatSuggestion(p))
+
+}
+
+func TestEstimatePowerUnknown(t *testing.T) {
+	t.Parallel()
+
+	// Unusable data is never reported as sufficient
+	for _, samples := range [][]float64{nil, {10}, {0, 10}, {-1, 10, 11}} {
+		p := estimatePower(samples, 0.95, 5, time.Millisecond)
+		assert.Equal(t, len(samples), p.Samples)
+		assert.True(t, p.Unknown)
+		assert.False(t, p.Sufficient())
+		assert.Equal(t, "unknown", formatSuggestion(p))
+	}
+}
+
+func TestAnalyze(t *testing.T) {
+	file := "test_power.json"
+	defer removeResults(file)
+
+	b := &B{config: config{filename: file, codec: jsonCodec{}}}
+	b.saveResult(Result{Name: "b", Samples: []float64{10, 11, 9, 10, 12}})
+	b.saveResult(Result{Name: "a", Samples: []float64{10, 10.5, 9.5, 10, 11}})
+	b.saveResult(Result{Name: "c", Samples: []float64{10}})
+
+	out, err := Analyze(WithFile(file), WithConfidence(95))
+	assert.NoError(t, err)
+	assert.Len(t, out, 2)
+	assert.Equal(t, "a", out[0].Name)
+	assert.Equal(t, "b", out[1].Name)
+	assert.Greater(t, out[1].Noise, out[0].Noise)
+
+	out, err = Analyze(WithFile(file), WithFilter("b"))
+	assert.NoError(t, err)
+	assert.Len(t, out, 1)
+}
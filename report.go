@@ -0,0 +1,180 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root
+
+package bench
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+)
+
+// resultRecord is the JSON report of a single benchmark.
+type resultRecord struct {
+	Type     string            `json:"type"`
+	Name     string            `json:"name"`
+	NsPerOp  float64           `json:"ns_op"`
+	OpsPerS  float64           `json:"ops_s"`
+	Allocs   float64           `json:"allocs_op"`
+	Bytes    float64           `json:"bytes_op"`
+	Samples  int               `json:"samples"`
+	Outliers int               `json:"outliers"`
+	Unstable bool              `json:"unstable"`
+	Tags     []string          `json:"tags,omitempty"`
+	VsPrev   *comparisonRecord `json:"vs_prev,omitempty"`
+	VsRef    *comparisonRecord `json:"vs_ref,omitempty"`
+}
+
+// comparisonRecord is the JSON report of a comparison between two sample sets,
+// along with those of the allocations and bytes against the previous run.
+type comparisonRecord struct {
+	Ratio       float64           `json:"ratio"`
+	RatioCI     [2]float64        `json:"ratio_ci"`
+	Significant bool              `json:"significant"`
+	Verdict     string            `json:"verdict"`
+	Unstable    bool              `json:"unstable"`
+	Allocs      *comparisonRecord `json:"allocs,omitempty"`
+	Bytes       *comparisonRecord `json:"bytes,omitempty"`
+}
+
+// summaryRecord is the JSON report of the suite-wide summaries.
+type summaryRecord struct {
+	Type   string         `json:"type"`
+	VsPrev *summaryFields `json:"vs_prev,omitempty"`
+	VsRef  *summaryFields `json:"vs_ref,omitempty"`
+}
+
+// summaryFields is the JSON report of a single suite-wide summary.
+type summaryFields struct {
+	Ratio        float64    `json:"ratio"`
+	RatioCI      [2]float64 `json:"ratio_ci"`
+	Verdict      string     `json:"verdict"`
+	Benchmarks   int        `json:"benchmarks"`
+	Improved     int        `json:"improved"`
+	Regressed    int        `json:"regressed"`
+	Similar      int        `json:"similar"`
+	Inconclusive int        `json:"inconclusive"`
+}
+
+// powerRecord is the JSON report of the power analysis of a benchmark.
+type powerRecord struct {
+	Type       string  `json:"type"`
+	Name       string  `json:"name"`
+	Samples    int     `json:"samples"`
+	Unknown    bool    `json:"unknown"`
+	Noise      float64 `json:"noise,omitempty"`
+	MinEffect  float64 `json:"min_effect,omitempty"`
+	Required   int     `json:"required,omitempty"`
+	Duration   int64   `json:"duration_ns,omitempty"`
+	Sufficient bool    `json:"sufficient"`
+}
+
+// openOutput directs the report to the configured output file, returning a
+// function which closes it.
+func (r *B) openOutput() (func(), error) {
+	if r.output == "" {
+		return func() {}, nil
+	}
+
+	f, err := os.Create(r.output)
+	if err != nil {
+		return nil, fmt.Errorf("bench: unable to create report: %w", err)
+	}
+
+	r.out = f
+	return func() {
+		if err := f.Close(); err != nil {
+			fmt.Printf("Error writing report: %v\n", err)
+		}
+		r.out = nil
+	}, nil
+}
+
+// writer returns where the report is written, the standard output by default.
+func (r *B) writer() io.Writer {
+	if r.out == nil {
+		return os.Stdout
+	}
+	return r.out
+}
+
+// printJSON writes a record of the JSON report on its own line.
+func (r *B) printJSON(record any) {
+	if err := json.NewEncoder(r.writer()).Encode(record); err != nil {
+		fmt.Printf("Error writing report: %v\n", err)
+	}
+}
+
+// newResultRecord returns the JSON report of a benchmark, along with its
+// comparisons when they were made.
+func newResultRecord(result Result, vsPrev, vsRef *Report) resultRecord {
+	nsPerOp := median(result.Samples)
+	return resultRecord{
+		Type:     "result",
+		Name:     result.Name,
+		NsPerOp:  finite(nsPerOp),
+		OpsPerS:  finite(1e9 / nsPerOp),
+		Allocs:   finite(median(result.Allocs)),
+		Bytes:    finite(median(result.Bytes)),
+		Samples:  len(result.Samples),
+		Outliers: result.Outliers,
+		Unstable: result.Unstable,
+		Tags:     result.Tags,
+		VsPrev:   newComparisonRecord(vsPrev),
+		VsRef:    newComparisonRecord(vsRef),
+	}
+}
+
+func newComparisonRecord(report *Report) *comparisonRecord {
+	if report == nil {
+		return nil
+	}
+
+	return &comparisonRecord{
+		Ratio:       finite(report.Ratio),
+		RatioCI:     [2]float64{finite(report.RatioCI[0]), finite(report.RatioCI[1])},
+		Significant: report.Significant,
+		Verdict:     report.Verdict.String(),
+		Unstable:    report.Unstable,
+		Allocs:      newComparisonRecord(report.Allocs),
+		Bytes:       newComparisonRecord(report.Bytes),
+	}
+}
+
+func newSummaryFields(s Summary) *summaryFields {
+	if s.Benchmarks < 2 {
+		return nil
+	}
+
+	return &summaryFields{
+		Ratio:        finite(s.Ratio),
+		RatioCI:      [2]float64{finite(s.RatioCI[0]), finite(s.RatioCI[1])},
+		Verdict:      s.Verdict.String(),
+		Benchmarks:   s.Benchmarks,
+		Improved:     s.Improved,
+		Regressed:    s.Regressed,
+		Similar:      s.Similar,
+		Inconclusive: s.Inconclusive,
+	}
+}
+
+func newPowerRecord(p Power) powerRecord {
+	record := powerRecord{Type: "power", Name: p.Name, Samples: p.Samples, Unknown: p.Unknown}
+	if !p.Unknown {
+		record.Noise = finite(p.Noise)
+		record.MinEffect = finite(p.MinEffect)
+		record.Required = p.Required
+		record.Duration = int64(p.Duration)
+		record.Sufficient = p.Sufficient()
+	}
+	return record
+}
+
+// finite replaces values which cannot be encoded in JSON with zero.
+func finite(v float64) float64 {
+	if !isFinite(v) {
+		return 0
+	}
+	return v
+}
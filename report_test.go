@@ -0,0 +1,71 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root
+
+package bench
+
+import (
+	"encoding/json"
+	"math"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRunJSONReport(t *testing.T) {
+	file, output := "test_report.json", "test_report.jsonl"
+	defer removeResults(file)
+	defer os.Remove(output)
+
+	for range 2 {
+		Run(func(b *B) {
+			b.Run("a", func(i int) {})
+			b.Run("b", func(i int) {}, func(i int) {})
+		}, WithFile(file), WithSamples(5), WithDuration(100_000), WithBootstrap(100), WithPower(),
+			WithFormat(FormatJSON), WithOutput(output))
+	}
+
+	data, err := os.ReadFile(output)
+	assert.NoError(t, err)
+
+	var types, names []string
+	for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		var record map[string]any
+		assert.NoError(t, json.Unmarshal([]byte(line), &record), line)
+		types = append(types, record["type"].(string))
+		if record["type"] == "result" {
+			names = append(names, record["name"].(string))
+			assert.Contains(t, record, "vs_prev")
+		}
+	}
+
+	// The output file holds only the report of the last run
+	assert.Equal(t, []string{"result", "result", "summary", "power", "power"}, types)
+	assert.Equal(t, []string{"a", "b"}, names)
+}
+
+func TestRunOutputError(t *testing.T) {
+	ran := false
+	Run(func(b *B) { ran = true }, WithDryRun(), WithOutput("missing/dir/report.txt"))
+	assert.False(t, ran)
+}
+
+func TestResultRecord(t *testing.T) {
+	record := newResultRecord(Result{Name: "a", Samples: []float64{10, 20, 30}, Tags: []string{"io"}}, nil, &Report{Ratio: math.Inf(1), Verdict: VerdictChanged})
+	assert.Equal(t, "result", record.Type)
+	assert.Equal(t, 20.0, record.NsPerOp)
+	assert.Equal(t, 3, record.Samples)
+	assert.Nil(t, record.VsPrev)
+	assert.Equal(t, 0.0, record.VsRef.Ratio)
+	assert.Equal(t, "changed", record.VsRef.Verdict)
+	assert.Nil(t, record.VsRef.Allocs)
+
+	record = newResultRecord(Result{Name: "a"}, &Report{Ratio: 1, Allocs: &Report{Ratio: 2, Significant: true, Verdict: VerdictChanged}}, nil)
+	assert.Equal(t, 2.0, record.VsPrev.Allocs.Ratio)
+	assert.True(t, record.VsPrev.Allocs.Significant)
+	assert.Nil(t, record.VsPrev.Bytes)
+
+	_, err := json.Marshal(record)
+	assert.NoError(t, err)
+}
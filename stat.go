@@ -5,24 +5,56 @@ package bench
 
 import (
 	"math"
-	"math/rand/v2"
 	"sort"
 
 	"gonum.org/v1/gonum/stat/distuv"
 )
 
-// Report represents the result of BCa Report inference.
+// Verdict classifies a comparison relative to the practical threshold band.
+type Verdict int
+
+const (
+	// VerdictInconclusive means the interval is too wide to conclude either way.
+	VerdictInconclusive Verdict = iota
+
+	// VerdictEquivalent means the interval lies fully inside the threshold band.
+	VerdictEquivalent
+
+	// VerdictChanged means the interval lies fully outside the threshold band.
+	VerdictChanged
+)
+
+// String returns the name of the verdict.
+func (v Verdict) String() string {
+	switch v {
+	case VerdictEquivalent:
+		return "equivalent"
+	case VerdictChanged:
+		return "changed"
+	default:
+		return "inconclusive"
+	}
+}
+
+// Report represents the result of statistical inference between two sample sets.
 type Report struct {
-	Delta         float64    // Delta is log(MedianVariant / MedianControl); positive is slower
+	Delta         float64    // Delta is the estimated log(variant / control); positive is slower
 	CI            [2]float64 // CI is the confidence interval for Delta
-	Ratio         float64    // Ratio is MedianVariant / MedianControl
+	Ratio         float64    // Ratio is exp(Delta)
 	RatioCI       [2]float64 // RatioCI is exp(CI)
 	MedianControl float64    // MedianControl is the median of the control group
 	MedianVariant float64    // MedianVariant is the median of the variant group
-	Confidence    float64    // Confidence is the confidence level (e.g., 0.95 for 95%)
+	Confidence    float64    // Confidence is the confidence level (e.g., 0.95 for 95%), after correction
+	Comparisons   int        // Comparisons is the number of comparisons the confidence was corrected for
+	PValue        float64    // PValue is the two-sided rank test p-value, set by MethodMannWhitney
 	Significant   bool       // Significant indicates statistical and practical significance
+	Verdict       Verdict    // Verdict is the three-way equivalence classification of CI
 	Degenerate    bool       // Degenerate indicates a bootstrap distribution without variation
+	Unstable      bool       // Unstable indicates that either sample set drifts over time
+	Paired        bool       // Paired indicates that samples were resampled as back-to-back pairs
 	Samples       int        // Samples is the number of bootstrap samples used
+	Allocs        *Report    // Allocs compares the allocs/op samples against the previous run, if both have them
+	Bytes         *Report    // Bytes compares the bytes/op samples against the previous run, if both have them
 }
 
 // bca performs BCa (Bias-Corrected accelerated) bootstrap inference comparing
@@ -51,22 +83,8 @@ func bcaWithSeed(control, experiment []float64, confidence float64, bootstrapSam
 			Samples:       bootstrapSamples,
 		}
 	}
-	rng := bootstrapRNG(len(control), len(experiment), bootstrapSamples, seed)
-
-	bootstrapStats := make([]float64, 0, bootstrapSamples)
-	for i := 0; i < bootstrapSamples; i++ {
-
-		// Resample with replacement using our seeded RNG
-		controlBootstrap := resampleWithReplacement(control, rng)
-		variantBootstrap := resampleWithReplacement(experiment, rng)
-
-		// Compute statistic for this bootstrap sample
-		controlBootMedian := medianInPlace(controlBootstrap)
-		variantBootMedian := medianInPlace(variantBootstrap)
-		if stat, ok := logRatio(controlBootMedian, variantBootMedian); ok {
-			bootstrapStats = append(bootstrapStats, stat)
-		}
-	}
+	seed1, seed2 := bootstrapSeed(len(control), len(experiment), bootstrapSamples, seed)
+	bootstrapStats := bootstrap(bootstrapSamples, seed1, seed2, medianRatio(control, experiment))
 	if len(bootstrapStats) == 0 {
 		return Report{
 			Delta:         originalLogRatio,
@@ -90,6 +108,7 @@ func bcaWithSeed(control, experiment []float64, confidence float64, bootstrapSam
 
 	// Step 5: More conservative significance detection
 	significant := !degenerate && isSignificant(lowerCI, upperCI, originalLogRatio, minChangePercent)
+	verdict := classify(significant, degenerate, lowerCI, upperCI, minChangePercent)
 
 	return Report{
 		Delta:         originalLogRatio,
@@ -100,19 +119,12 @@ func bcaWithSeed(control, experiment []float64, confidence float64, bootstrapSam
 		MedianVariant: medianVariant,
 		Confidence:    confidence,
 		Significant:   significant,
+		Verdict:       verdict,
 		Degenerate:    degenerate,
 		Samples:       len(bootstrapStats),
 	}
 }
 
-func bootstrapRNG(controlSamples, experimentSamples, bootstrapSamples int, seed uint64) *rand.Rand {
-	seed1 := uint64(controlSamples)<<32 ^ uint64(experimentSamples)<<16 ^ uint64(bootstrapSamples) ^ 0x9e3779b97f4a7c15
-	seed2 := uint64(experimentSamples)<<32 ^ uint64(controlSamples)<<16 ^ uint64(bootstrapSamples) ^ 0xbf58476d1ce4e5b9
-	seed1 ^= seed
-	seed2 ^= seed<<1 | seed>>63
-	return rand.New(rand.NewPCG(seed1, seed2))
-}
-
 // isSignificant requires the log-ratio interval to clear the practical threshold.
 func isSignificant(lowerCI, upperCI, logRatio, minChangePercent float64) bool {
 	if !isFinite(lowerCI) || !isFinite(upperCI) || !isFinite(logRatio) {
@@ -127,16 +139,31 @@ func isSignificant(lowerCI, upperCI, logRatio, minChangePercent float64) bool {
 	return lowerCI > threshold || upperCI < -threshold
 }
 
-// resampleWithReplacement performs bootstrap resampling with replacement using provided RNG
-func resampleWithReplacement(data []float64, rng *rand.Rand) []float64 {
-	n := len(data)
-	resampled := make([]float64, n)
-	for i := 0; i < n; i++ {
-		idx := rng.IntN(n)
-		resampled[i] = data[idx]
+// isEquivalent performs two one-sided tests (TOST) by requiring the log-ratio
+// interval to lie strictly inside the practical threshold band.
+func isEquivalent(lowerCI, upperCI, minChangePercent float64) bool {
+	if !isFinite(lowerCI) || !isFinite(upperCI) {
+		return false
 	}
 
-	return resampled
+	threshold := math.Log1p(math.Max(0, minChangePercent) / 100.0)
+	return lowerCI > -threshold && upperCI < threshold
+}
+
+// classify derives the three-way verdict of a comparison. A degenerate interval
+// is only equivalent when it shows no change at all, e.g. for identical constant
+// samples.
+func classify(significant, degenerate bool, lowerCI, upperCI, minChangePercent float64) Verdict {
+	switch {
+	case significant:
+		return VerdictChanged
+	case degenerate && lowerCI == 0 && upperCI == 0:
+		return VerdictEquivalent
+	case !degenerate && isEquivalent(lowerCI, upperCI, minChangePercent):
+		return VerdictEquivalent
+	default:
+		return VerdictInconclusive
+	}
 }
 
 // median calculates the median of a slice of float64.
@@ -149,9 +176,20 @@ func median(data []float64) float64 {
 	return medianInPlace(clone)
 }
 
+// medianInPlace calculates the median using selection, reordering the data.
 func medianInPlace(data []float64) float64 {
-	sort.Float64s(data)
-	return medianSorted(data)
+	n := len(data)
+	upper := selectKth(data, n/2)
+	if n%2 == 1 {
+		return upper
+	}
+
+	// After selection, the lower middle value is the largest of the lower half
+	lower := data[0]
+	for _, v := range data[1 : n/2] {
+		lower = max(lower, v)
+	}
+	return (lower + upper) / 2.0
 }
 
 func medianSorted(data []float64) float64 {
@@ -210,9 +248,10 @@ func computeAcceleration(control, experiment []float64) float64 {
 		return 0
 	}
 
+	jackSample := make([]float64, 0, max(n1, n2))
 	controlJack := make([]float64, n1)
 	for i := 0; i < n1; i++ {
-		jackSample := make([]float64, 0, n1-1)
+		jackSample = jackSample[:0]
 		for j := 0; j < n1; j++ {
 			if j != i {
 				jackSample = append(jackSample, control[j])
@@ -227,7 +266,7 @@ func computeAcceleration(control, experiment []float64) float64 {
 
 	experimentJack := make([]float64, n2)
 	for i := 0; i < n2; i++ {
-		jackSample := make([]float64, 0, n2-1)
+		jackSample = jackSample[:0]
 		for j := 0; j < n2; j++ {
 			if j != i {
 				jackSample = append(jackSample, experiment[j])
@@ -367,6 +406,17 @@ func degenerateBootstrap(stats []float64) bool {
 	return true
 }
 
+// bonferroni adjusts a confidence level so that m comparisons together keep the
+// family-wise error rate at 1 - confidence.
+func bonferroni(confidence float64, m int) float64 {
+	confidence = normalizeConfidence(confidence)
+	if m <= 1 {
+		return confidence
+	}
+
+	return 1 - (1-confidence)/float64(m)
+}
+
 func normalizeConfidence(confidence float64) float64 {
 	if !isFinite(confidence) || confidence <= 0 || confidence >= 1 {
 		return defaultConfidence / 100.0
@@ -0,0 +1,152 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root
+
+package bench
+
+import (
+	"math"
+	"math/rand/v2"
+	"runtime"
+	"sync"
+	"sync/atomic"
+)
+
+// bootstrapChunk is the number of resamples drawn from a single PCG stream. The
+// chunk layout is fixed, so results do not depend on the number of workers.
+const bootstrapChunk = 1024
+
+// resampler computes a single bootstrap statistic using the provided RNG. Each
+// worker gets its own resampler, so it may reuse internal buffers.
+type resampler func(rng *rand.Rand) (float64, bool)
+
+// bootstrap draws the requested number of bootstrap statistics in parallel and
+// returns the valid ones in a deterministic order.
+func bootstrap(samples int, seed1, seed2 uint64, newResampler func() resampler) []float64 {
+	if samples <= 0 {
+		return nil
+	}
+
+	stats := make([]float64, samples)
+	chunks := (samples + bootstrapChunk - 1) / bootstrapChunk
+	workers := min(runtime.GOMAXPROCS(0), chunks)
+
+	var next atomic.Int64
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			pcg := rand.NewPCG(0, 0)
+			rng := rand.New(pcg)
+			fn := newResampler()
+			for {
+				chunk := int(next.Add(1) - 1)
+				if chunk >= chunks {
+					return
+				}
+
+				// Every chunk has its own stream, derived from the seed and index
+				pcg.Seed(seed1^splitmix(uint64(chunk)), seed2^splitmix(^uint64(chunk)))
+				lo := chunk * bootstrapChunk
+				hi := min(lo+bootstrapChunk, samples)
+				for i := lo; i < hi; i++ {
+					stat, ok := fn(rng)
+					if !ok {
+						stat = math.NaN()
+					}
+					stats[i] = stat
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	// Drop invalid statistics while preserving the order
+	valid := stats[:0]
+	for _, stat := range stats {
+		if !math.IsNaN(stat) {
+			valid = append(valid, stat)
+		}
+	}
+	return valid
+}
+
+// medianRatio returns a resampler of the log ratio of medians of two independent
+// sample sets, reusing its buffers across resamples.
+func medianRatio(control, experiment []float64) func() resampler {
+	return func() resampler {
+		controlBuf := make([]float64, len(control))
+		variantBuf := make([]float64, len(experiment))
+		return func(rng *rand.Rand) (float64, bool) {
+			resampleInto(controlBuf, control, rng)
+			resampleInto(variantBuf, experiment, rng)
+			return logRatio(medianInPlace(controlBuf), medianInPlace(variantBuf))
+		}
+	}
+}
+
+// bootstrapSeed derives the base PCG seed for a comparison.
+func bootstrapSeed(controlSamples, experimentSamples, bootstrapSamples int, seed uint64) (uint64, uint64) {
+	seed1 := uint64(controlSamples)<<32 ^ uint64(experimentSamples)<<16 ^ uint64(bootstrapSamples) ^ 0x9e3779b97f4a7c15
+	seed2 := uint64(experimentSamples)<<32 ^ uint64(controlSamples)<<16 ^ uint64(bootstrapSamples) ^ 0xbf58476d1ce4e5b9
+	seed1 ^= seed
+	seed2 ^= seed<<1 | seed>>63
+	return seed1, seed2
+}
+
+// splitmix scrambles a value with the SplitMix64 finalizer.
+func splitmix(x uint64) uint64 {
+	x += 0x9e3779b97f4a7c15
+	x = (x ^ (x >> 30)) * 0xbf58476d1ce4e5b9
+	x = (x ^ (x >> 27)) * 0x94d049bb133111eb
+	return x ^ (x >> 31)
+}
+
+// resampleInto fills dst by sampling data with replacement.
+func resampleInto(dst, data []float64, rng *rand.Rand) {
+	n := len(data)
+	for i := range dst {
+		dst[i] = data[rng.IntN(n)]
+	}
+}
+
+// selectKth partially orders data so that data[k] holds the k-th smallest value,
+// with smaller or equal values before it, and returns that value. It uses a
+// three-way partition so that tied values, which are common in resampled data
+// and allocation counts, are settled in a single pass.
+func selectKth(data []float64, k int) float64 {
+	lo, hi := 0, len(data)-1
+	for lo < hi {
+		// Median-of-three pivot
+		mid := lo + (hi-lo)/2
+		a, b, c := data[lo], data[mid], data[hi]
+		pivot := max(min(a, b), min(max(a, b), c))
+
+		// Partition into values below, equal to and above the pivot
+		lt, i, gt := lo, lo, hi
+		for i <= gt {
+			switch v := data[i]; {
+			case v < pivot:
+				data[lt], data[i] = v, data[lt]
+				lt++
+				i++
+			case v > pivot:
+				data[gt], data[i] = v, data[gt]
+				gt--
+			default:
+				i++
+			}
+		}
+
+		switch {
+		case k < lt:
+			hi = lt - 1
+		case k > gt:
+			lo = gt + 1
+		default:
+			return data[k]
+		}
+	}
+	return data[k]
+}
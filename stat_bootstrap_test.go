@@ -0,0 +1,115 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root
+
+package bench
+
+import (
+	"math/rand/v2"
+	"runtime"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBootstrapIsDeterministicAcrossWorkers(t *testing.T) {
+	control := []float64{10.0, 12.0, 11.0, 13.0, 9.0, 11.5, 10.5, 12.5}
+	experiment := []float64{8.0, 9.0, 7.5, 8.5, 7.0, 8.0, 9.5, 8.2}
+
+	prev := runtime.GOMAXPROCS(1)
+	defer runtime.GOMAXPROCS(prev)
+	serial := bcaWithSeed(control, experiment, 0.95, 10000, defaultThreshold, 42)
+
+	runtime.GOMAXPROCS(8)
+	parallel := bcaWithSeed(control, experiment, 0.95, 10000, defaultThreshold, 42)
+	assert.Equal(t, serial, parallel)
+
+	// A different seed yields a different bootstrap distribution
+	other := bcaWithSeed(control, experiment, 0.95, 10000, defaultThreshold, 43)
+	assert.NotEqual(t, serial.CI, other.CI)
+}
+
+func TestBootstrapDropsInvalidStats(t *testing.T) {
+	t.Parallel()
+
+	stats := bootstrap(3000, 1, 2, func() resampler {
+		return func(rng *rand.Rand) (float64, bool) {
+			v := rng.Float64()
+			return v, v < 0.5
+		}
+	})
+
+	assert.Greater(t, len(stats), 1000)
+	assert.Less(t, len(stats), 2000)
+	assert.Empty(t, bootstrap(0, 1, 2, nil))
+}
+
+func TestMedianRatioDoesNotAllocate(t *testing.T) {
+	control := []float64{10.0, 12.0, 11.0, 13.0, 9.0, 11.5, 10.5, 12.5}
+	experiment := []float64{8.0, 9.0, 7.5, 8.5, 7.0, 8.0, 9.5, 8.2}
+
+	fn := medianRatio(control, experiment)()
+	rng := rand.New(rand.NewPCG(1, 2))
+	allocs := testing.AllocsPerRun(100, func() {
+		fn(rng)
+	})
+	assert.Equal(t, 0.0, allocs)
+}
+
+func TestSelectKth(t *testing.T) {
+	t.Parallel()
+
+	rng := rand.New(rand.NewPCG(3, 4))
+	for n := 1; n < 50; n++ {
+		data := make([]float64, n)
+		for i := range data {
+			data[i] = float64(rng.IntN(10))
+		}
+
+		sorted := append([]float64(nil), data...)
+		sort.Float64s(sorted)
+		k := rng.IntN(n)
+		assert.Equal(t, sorted[k], selectKth(append([]float64(nil), data...), k))
+		assert.Equal(t, medianSorted(sorted), medianInPlace(data))
+	}
+}
+
+func TestSelectKthTies(t *testing.T) {
+	t.Parallel()
+
+	// All-equal input is settled in a single linear pass
+	data := make([]float64, 1<<20)
+	for i := range data {
+		data[i] = 3
+	}
+	assert.Equal(t, 3.0, selectKth(data, len(data)/2))
+
+	data = []float64{2, 1, 2, 2, 3, 2, 2, 1, 3, 2}
+	assert.Equal(t, 2.0, medianInPlace(data))
+	assert.Equal(t, 1.0, selectKth(data, 1))
+	assert.Equal(t, 3.0, selectKth(data, 9))
+}
+
+func BenchmarkSelectKth(b *testing.B) {
+	for _, tc := range []struct {
+		name string
+		gen  func(i int) float64
+	}{
+		{"constant", func(int) float64 { return 1 }},
+		{"ties", func(i int) float64 { return float64(i % 3) }},
+		{"distinct", func(i int) float64 { return float64((i * 7919) % 1000) }},
+	} {
+		data := make([]float64, 1000)
+		work := make([]float64, len(data))
+		for i := range data {
+			data[i] = tc.gen(i)
+		}
+
+		b.Run(tc.name, func(b *testing.B) {
+			for b.Loop() {
+				copy(work, data)
+				selectKth(work, len(work)/2)
+			}
+		})
+	}
+}
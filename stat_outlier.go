@@ -0,0 +1,85 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root
+
+package bench
+
+import (
+	"math"
+	"sort"
+)
+
+const (
+	// tukeyFence is the multiple of the interquartile range beyond the quartiles
+	tukeyFence = 1.5
+
+	// madFence is the modified z-score beyond which a sample is an outlier
+	madFence = 3.5
+
+	// outlierWarning is the fraction of outliers above which a run is flagged
+	outlierWarning = 0.05
+)
+
+// Fence selects the rule used to detect outliers in timing samples.
+type Fence int
+
+const (
+	// FenceTukey flags samples outside 1.5 interquartile ranges of the quartiles.
+	FenceTukey Fence = iota
+
+	// FenceMAD flags samples whose modified z-score, based on the median absolute
+	// deviation, exceeds 3.5.
+	FenceMAD
+)
+
+// fences returns the inclusive range of values which are not outliers.
+func fences(data []float64, fence Fence) (lo, hi float64) {
+	if len(data) == 0 {
+		return math.Inf(-1), math.Inf(1)
+	}
+
+	switch fence {
+	case FenceMAD:
+		center := median(data)
+		spread := madFence * robustSigma(data)
+		return center - spread, center + spread
+	default:
+		sorted := append([]float64(nil), data...)
+		sort.Float64s(sorted)
+		q1, q3 := percentile(sorted, 0.25), percentile(sorted, 0.75)
+		iqr := q3 - q1
+		return q1 - tukeyFence*iqr, q3 + tukeyFence*iqr
+	}
+}
+
+// countOutliers returns the number of samples outside the fences.
+func countOutliers(data []float64, fence Fence) (count int) {
+	lo, hi := fences(data, fence)
+	for _, v := range data {
+		if v < lo || v > hi {
+			count++
+		}
+	}
+	return
+}
+
+// trimOutliers returns a copy of the samples without outliers. The original
+// samples are returned when trimming would leave too few samples to compare.
+func trimOutliers(data []float64, fence Fence) []float64 {
+	lo, hi := fences(data, fence)
+	trimmed := make([]float64, 0, len(data))
+	for _, v := range data {
+		if v >= lo && v <= hi {
+			trimmed = append(trimmed, v)
+		}
+	}
+
+	if len(trimmed) < minSamples {
+		return data
+	}
+	return trimmed
+}
+
+// hasOutlierWarning reports whether the share of outliers warrants a warning.
+func hasOutlierWarning(outliers, samples int) bool {
+	return samples > 0 && float64(outliers)/float64(samples) > outlierWarning
+}
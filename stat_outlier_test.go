@@ -0,0 +1,58 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root
+
+package bench
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCountOutliers(t *testing.T) {
+	t.Parallel()
+
+	data := []float64{10, 11, 10.5, 9.5, 10.2, 9.8, 10.1, 50}
+	assert.Equal(t, 1, countOutliers(data, FenceTukey))
+	assert.Equal(t, 1, countOutliers(data, FenceMAD))
+
+	clean := []float64{10, 11, 10.5, 9.5, 10.2, 9.8, 10.1}
+	assert.Equal(t, 0, countOutliers(clean, FenceTukey))
+	assert.Equal(t, 0, countOutliers(clean, FenceMAD))
+	assert.Equal(t, 0, countOutliers(nil, FenceTukey))
+}
+
+func TestTrimOutliers(t *testing.T) {
+	t.Parallel()
+
+	data := []float64{10, 11, 10.5, 9.5, 10.2, 9.8, 10.1, 50}
+	trimmed := trimOutliers(data, FenceTukey)
+	assert.Len(t, trimmed, 7)
+	assert.NotContains(t, trimmed, 50.0)
+	assert.Len(t, data, 8, "input should not be modified")
+
+	// Trimming never leaves fewer samples than required for a comparison
+	sparse := []float64{1, 100}
+	assert.Equal(t, sparse, trimOutliers(sparse, FenceMAD))
+}
+
+func TestOutlierWarning(t *testing.T) {
+	t.Parallel()
+
+	assert.False(t, hasOutlierWarning(0, 0))
+	assert.False(t, hasOutlierWarning(5, 100))
+	assert.True(t, hasOutlierWarning(6, 100))
+	assert.Equal(t, "1.0 ms ⚠", formatTimeWithWarnings(1e6, Result{Samples: make([]float64, 100), Outliers: 10}))
+	assert.Equal(t, "1.0 ms", formatTimeWithWarnings(1e6, Result{Samples: make([]float64, 100), Outliers: 1}))
+}
+
+func TestCompareWithTrim(t *testing.T) {
+	t.Parallel()
+
+	control := []float64{10, 10.1, 9.9, 10, 10.2, 9.8, 10.1, 9.9}
+	variant := []float64{10, 10.1, 9.9, 10, 10.2, 9.8, 10.1, 200}
+
+	b := &B{config: config{confidence: 95, threshold: defaultThreshold, bootstrap: 1000, method: MethodMannWhitney, trim: true}}
+	report := b.compare(control, variant)
+	assert.Equal(t, 10.0, report.MedianVariant)
+}
@@ -0,0 +1,148 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root
+
+package bench
+
+import (
+	"math"
+	"math/rand/v2"
+)
+
+// bcaPaired performs BCa bootstrap inference on naturally paired samples, where
+// control[i] and experiment[i] were measured back-to-back. Pairs are resampled
+// together and the statistic is the median of per-pair log ratios, so noise
+// shared by both members of a pair cancels out.
+func bcaPaired(control, experiment []float64, confidence float64, bootstrapSamples int, minChangePercent float64, seed uint64) Report {
+	if len(control) != len(experiment) {
+		return bcaWithSeed(control, experiment, confidence, bootstrapSamples, minChangePercent, seed)
+	}
+	if len(control) == 0 || bootstrapSamples <= 0 {
+		return Report{}
+	}
+	confidence = normalizeConfidence(confidence)
+
+	medianControl := median(control)
+	medianVariant := median(experiment)
+	diffs, ok := pairedLogRatios(control, experiment)
+	if !ok {
+		return Report{
+			MedianControl: medianControl,
+			MedianVariant: medianVariant,
+			Confidence:    confidence,
+			Samples:       bootstrapSamples,
+		}
+	}
+
+	originalShift := median(diffs)
+	seed1, seed2 := bootstrapSeed(len(control), len(experiment), bootstrapSamples, seed)
+	bootstrapStats := bootstrap(bootstrapSamples, seed1, seed2, medianShift(diffs))
+
+	biasCorrection := computeBiasCorrection(originalShift, bootstrapStats)
+	acceleration := computePairedAcceleration(diffs)
+	degenerate := degenerateBootstrap(bootstrapStats)
+
+	alpha := 1.0 - confidence
+	lowerCI, upperCI := computeBCaCI(bootstrapStats, biasCorrection, acceleration, alpha)
+
+	significant := !degenerate && isSignificant(lowerCI, upperCI, originalShift, minChangePercent)
+	verdict := classify(significant, degenerate, lowerCI, upperCI, minChangePercent)
+
+	return Report{
+		Delta:         originalShift,
+		CI:            [2]float64{lowerCI, upperCI},
+		Ratio:         math.Exp(originalShift),
+		RatioCI:       [2]float64{math.Exp(lowerCI), math.Exp(upperCI)},
+		MedianControl: medianControl,
+		MedianVariant: medianVariant,
+		Confidence:    confidence,
+		Significant:   significant,
+		Verdict:       verdict,
+		Degenerate:    degenerate,
+		Paired:        true,
+		Samples:       len(bootstrapStats),
+	}
+}
+
+// pairedLogRatios returns log(experiment[i] / control[i]) for every pair.
+func pairedLogRatios(control, experiment []float64) ([]float64, bool) {
+	diffs := make([]float64, len(control))
+	for i := range control {
+		v, ok := logRatio(control[i], experiment[i])
+		if !ok {
+			return nil, false
+		}
+		diffs[i] = v
+	}
+	return diffs, true
+}
+
+// medianShift returns a resampler of the median of paired log ratios.
+func medianShift(diffs []float64) func() resampler {
+	return func() resampler {
+		buffer := make([]float64, len(diffs))
+		return func(rng *rand.Rand) (float64, bool) {
+			resampleInto(buffer, diffs, rng)
+			return medianInPlace(buffer), true
+		}
+	}
+}
+
+// computePairedAcceleration computes the one-sample BCa acceleration parameter
+// of the median paired log ratio using jackknife.
+func computePairedAcceleration(diffs []float64) float64 {
+	return jackknifeAcceleration(diffs, medianInPlace)
+}
+
+// jackknifeAcceleration computes the one-sample BCa acceleration parameter of
+// an arbitrary statistic using jackknife. The statistic may reorder its input.
+func jackknifeAcceleration(data []float64, statistic func([]float64) float64) float64 {
+	n := len(data)
+	if n < 2 {
+		return 0
+	}
+
+	jackSample := make([]float64, 0, n)
+	jackStats := make([]float64, n)
+	for i := 0; i < n; i++ {
+		jackSample = jackSample[:0]
+		jackSample = append(jackSample, data[:i]...)
+		jackSample = append(jackSample, data[i+1:]...)
+		jackStats[i] = statistic(jackSample)
+	}
+
+	sumCubed, sumSquared := accelerationTerms(jackStats)
+	if sumSquared == 0 {
+		return 0
+	}
+
+	acceleration := sumCubed / (6.0 * math.Pow(sumSquared, 1.5))
+	if !isFinite(acceleration) {
+		return 0
+	}
+
+	return acceleration
+}
+
+// trimPairs drops the pairs whose log ratio is an outlier, keeping the pairing
+// of the remaining samples intact.
+func trimPairs(control, experiment []float64, fence Fence) ([]float64, []float64) {
+	diffs, ok := pairedLogRatios(control, experiment)
+	if !ok {
+		return control, experiment
+	}
+
+	lo, hi := fences(diffs, fence)
+	trimmedControl := make([]float64, 0, len(control))
+	trimmedVariant := make([]float64, 0, len(experiment))
+	for i, d := range diffs {
+		if d >= lo && d <= hi {
+			trimmedControl = append(trimmedControl, control[i])
+			trimmedVariant = append(trimmedVariant, experiment[i])
+		}
+	}
+
+	if len(trimmedControl) < minSamples {
+		return control, experiment
+	}
+	return trimmedControl, trimmedVariant
+}
@@ -0,0 +1,80 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root
+
+package bench
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBCaPairedCancelsSharedNoise(t *testing.T) {
+	t.Parallel()
+
+	// Each pair shares a large common factor, while the experiment is always 10% faster
+	shared := []float64{1.0, 1.5, 0.8, 1.3, 0.9, 1.6, 1.1, 0.7, 1.4, 1.2}
+	control := make([]float64, len(shared))
+	experiment := make([]float64, len(shared))
+	for i, s := range shared {
+		control[i] = 100 * s
+		experiment[i] = 90 * s * (1 + 0.001*float64(i%3))
+	}
+
+	paired := bcaPaired(control, experiment, 0.95, 2000, defaultThreshold, 0)
+	unpaired := bca(control, experiment, 0.95, 2000, defaultThreshold)
+
+	assert.True(t, paired.Paired)
+	assert.True(t, paired.Significant, "paired comparison should detect the change")
+	assert.False(t, unpaired.Significant, "independent comparison is swamped by shared noise")
+	assert.InDelta(t, math.Log(0.9), paired.Delta, 0.01)
+	assert.Less(t, paired.CI[1]-paired.CI[0], unpaired.CI[1]-unpaired.CI[0])
+}
+
+func TestBCaPairedEdgeCases(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, Report{}, bcaPaired(nil, nil, 0.95, 100, defaultThreshold, 0))
+
+	// Mismatched lengths fall back to independent resampling
+	report := bcaPaired([]float64{1, 2, 3}, []float64{1, 2}, 0.95, 100, defaultThreshold, 0)
+	assert.False(t, report.Paired)
+
+	report = bcaPaired([]float64{0, 1}, []float64{1, 2}, 0.95, 100, defaultThreshold, 0)
+	assert.False(t, report.Significant)
+	assert.Equal(t, 1.5, report.MedianVariant)
+}
+
+func TestTrimPairs(t *testing.T) {
+	t.Parallel()
+
+	control := []float64{10, 10, 10, 10, 10, 10, 10, 10}
+	variant := []float64{9, 9.1, 8.9, 9, 9.1, 8.9, 9, 30}
+
+	c, v := trimPairs(control, variant, FenceTukey)
+	assert.Len(t, c, 7)
+	assert.Len(t, v, 7)
+	assert.NotContains(t, v, 30.0)
+}
+
+func TestCompareRefUsesPairing(t *testing.T) {
+	t.Parallel()
+
+	control := []float64{10, 12, 11, 13, 9, 11.5, 10.5, 12.5}
+	variant := []float64{9, 10.8, 9.9, 11.7, 8.1, 10.35, 9.45, 11.25}
+
+	b := &B{config: config{confidence: 95, threshold: defaultThreshold, bootstrap: 1000}}
+	assert.False(t, b.compareRef(control, variant).Paired)
+
+	b.paired = true
+	report := b.compareRef(control, variant)
+	assert.True(t, report.Paired)
+	assert.True(t, report.Significant)
+
+	// The rank test has no paired mode
+	b.method = MethodMannWhitney
+	report = b.compareRef(control, variant)
+	assert.False(t, report.Paired)
+	assert.Greater(t, report.PValue, 0.0)
+}
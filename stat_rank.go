@@ -0,0 +1,162 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root
+
+package bench
+
+import (
+	"math"
+	"sort"
+
+	"gonum.org/v1/gonum/stat/distuv"
+)
+
+// mannWhitney performs a Mann-Whitney U test comparing two samples and estimates
+// the Hodges-Lehmann shift of log timings with its distribution-free interval.
+func mannWhitney(control, experiment []float64, confidence float64, minChangePercent float64) Report {
+	if len(control) == 0 || len(experiment) == 0 {
+		return Report{}
+	}
+	confidence = normalizeConfidence(confidence)
+
+	medianControl := median(control)
+	medianVariant := median(experiment)
+	logControl, okControl := logSamples(control)
+	logVariant, okVariant := logSamples(experiment)
+	if !okControl || !okVariant {
+		return Report{
+			MedianControl: medianControl,
+			MedianVariant: medianVariant,
+			Confidence:    confidence,
+		}
+	}
+
+	// Hodges-Lehmann estimate is the median of all pairwise log differences
+	diffs := pairwiseDiffs(logControl, logVariant)
+	shift := medianSorted(diffs)
+
+	alpha := 1.0 - confidence
+	lowerCI, upperCI := hodgesLehmannCI(diffs, len(control), len(experiment), alpha)
+	pValue := mannWhitneyPValue(logControl, logVariant)
+	degenerate := diffs[0] == diffs[len(diffs)-1]
+
+	significant := !degenerate && pValue < alpha && isSignificant(lowerCI, upperCI, shift, minChangePercent)
+	verdict := classify(significant, degenerate, lowerCI, upperCI, minChangePercent)
+	return Report{
+		Delta:         shift,
+		CI:            [2]float64{lowerCI, upperCI},
+		Ratio:         math.Exp(shift),
+		RatioCI:       [2]float64{math.Exp(lowerCI), math.Exp(upperCI)},
+		MedianControl: medianControl,
+		MedianVariant: medianVariant,
+		Confidence:    confidence,
+		PValue:        pValue,
+		Significant:   significant,
+		Verdict:       verdict,
+		Degenerate:    degenerate,
+	}
+}
+
+// mannWhitneyPValue computes the two-sided p-value of the U statistic using the
+// normal approximation with tie and continuity corrections.
+func mannWhitneyPValue(control, experiment []float64) float64 {
+	n1, n2 := float64(len(control)), float64(len(experiment))
+	n := n1 + n2
+
+	ranks, ties := rankSum(control, experiment)
+	u := ranks - n2*(n2+1)/2
+	mu := n1 * n2 / 2
+	variance := n1 * n2 / 12 * ((n + 1) - ties/(n*(n-1)))
+	if variance <= 0 || !isFinite(variance) {
+		return 1
+	}
+
+	diff := u - mu
+	switch {
+	case diff > 0:
+		diff = math.Max(0, diff-0.5)
+	case diff < 0:
+		diff = math.Min(0, diff+0.5)
+	}
+
+	z := math.Abs(diff) / math.Sqrt(variance)
+	return math.Min(1, 2*(1-distuv.UnitNormal.CDF(z)))
+}
+
+// rankSum returns the sum of mid-ranks of the experiment samples within the
+// combined sample, along with the tie correction term sum(t^3 - t).
+func rankSum(control, experiment []float64) (sum, ties float64) {
+	type entry struct {
+		value   float64
+		variant bool
+	}
+
+	combined := make([]entry, 0, len(control)+len(experiment))
+	for _, v := range control {
+		combined = append(combined, entry{value: v})
+	}
+	for _, v := range experiment {
+		combined = append(combined, entry{value: v, variant: true})
+	}
+	sort.Slice(combined, func(i, j int) bool {
+		return combined[i].value < combined[j].value
+	})
+
+	for i := 0; i < len(combined); {
+		j := i + 1
+		for j < len(combined) && combined[j].value == combined[i].value {
+			j++
+		}
+
+		// Tied values share the average of the ranks they span
+		rank := float64(i+j+1) / 2
+		for k := i; k < j; k++ {
+			if combined[k].variant {
+				sum += rank
+			}
+		}
+
+		t := float64(j - i)
+		ties += t*t*t - t
+		i = j
+	}
+	return sum, ties
+}
+
+// hodgesLehmannCI returns the distribution-free confidence interval for the
+// shift from the order statistics of the sorted pairwise differences.
+func hodgesLehmannCI(sortedDiffs []float64, n1, n2 int, alpha float64) (float64, float64) {
+	m := float64(n1 * n2)
+	z := distuv.UnitNormal.Quantile(1 - alpha/2)
+	k := int(math.Floor(m/2 - z*math.Sqrt(m*float64(n1+n2+1)/12)))
+	if k < 1 {
+		k = 1
+	}
+
+	return sortedDiffs[k-1], sortedDiffs[len(sortedDiffs)-k]
+}
+
+// pairwiseDiffs returns all sorted differences experiment[j] - control[i].
+func pairwiseDiffs(control, experiment []float64) []float64 {
+	diffs := make([]float64, 0, len(control)*len(experiment))
+	for _, y := range experiment {
+		for _, x := range control {
+			diffs = append(diffs, y-x)
+		}
+	}
+
+	sort.Float64s(diffs)
+	return diffs
+}
+
+// logSamples returns the natural logarithm of every sample, failing when any
+// sample is not strictly positive.
+func logSamples(data []float64) ([]float64, bool) {
+	out := make([]float64, len(data))
+	for i, v := range data {
+		if v <= 0 || !isFinite(v) {
+			return nil, false
+		}
+		out[i] = math.Log(v)
+	}
+	return out, true
+}
@@ -0,0 +1,87 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root
+
+package bench
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMannWhitneyBasic(t *testing.T) {
+	t.Parallel()
+
+	control := []float64{10.0, 12.0, 11.0, 13.0, 9.0, 11.5, 10.5, 12.5}
+	experiment := []float64{8.0, 9.0, 7.5, 8.5, 7.0, 8.0, 9.5, 8.2}
+
+	result := mannWhitney(control, experiment, 0.95, defaultThreshold)
+
+	assert.True(t, result.Delta < 0, "Expected negative shift (experiment faster)")
+	assert.True(t, result.CI[0] <= result.Delta && result.Delta <= result.CI[1], "Shift should lie within its interval")
+	assert.True(t, result.Ratio < 1, "Expected experiment/control ratio below 1")
+	assert.True(t, result.PValue < 0.05, "Clear separation should yield a small p-value")
+	assert.Equal(t, 0.95, result.Confidence)
+	assert.True(t, result.Significant)
+}
+
+func TestMannWhitneyPValue(t *testing.T) {
+	t.Parallel()
+
+	// Fully separated groups of five; reference value from the normal approximation
+	// with continuity correction.
+	control := []float64{1, 2, 3, 4, 5}
+	experiment := []float64{6, 7, 8, 9, 10}
+
+	assert.InDelta(t, 0.01219, mannWhitneyPValue(control, experiment), 1e-4)
+	assert.InDelta(t, 0.01219, mannWhitneyPValue(experiment, control), 1e-4)
+	assert.Equal(t, 1.0, mannWhitneyPValue([]float64{1, 1, 1}, []float64{1, 1, 1}))
+}
+
+func TestMannWhitneyIdentical(t *testing.T) {
+	t.Parallel()
+
+	identical := []float64{10.0, 10.0, 10.0, 10.0, 10.0}
+	result := mannWhitney(identical, identical, 0.95, defaultThreshold)
+
+	assert.True(t, result.Degenerate)
+	assert.False(t, result.Significant)
+	assert.Equal(t, 0.0, result.Delta)
+	assert.Equal(t, 1.0, result.PValue)
+}
+
+func TestMannWhitneyHodgesLehmann(t *testing.T) {
+	t.Parallel()
+
+	control := []float64{100, 101, 102, 103, 104}
+	experiment := []float64{200, 202, 204, 206, 208}
+
+	result := mannWhitney(control, experiment, 0.95, defaultThreshold)
+	assert.InDelta(t, math.Log(2), result.Delta, 0.01)
+	assert.True(t, result.Significant)
+}
+
+func TestMannWhitneyEdgeCases(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, Report{}, mannWhitney(nil, []float64{1}, 0.95, defaultThreshold))
+	assert.Equal(t, Report{}, mannWhitney([]float64{1}, nil, 0.95, defaultThreshold))
+
+	result := mannWhitney([]float64{0, 1}, []float64{1, 2}, 0.95, defaultThreshold)
+	assert.False(t, result.Significant)
+	assert.Equal(t, 1.5, result.MedianVariant)
+}
+
+func TestCompareUsesMethod(t *testing.T) {
+	t.Parallel()
+
+	control := []float64{10.0, 12.0, 11.0, 13.0, 9.0, 11.5, 10.5, 12.5}
+	experiment := []float64{8.0, 9.0, 7.5, 8.5, 7.0, 8.0, 9.5, 8.2}
+
+	b := &B{config: config{confidence: 95, threshold: defaultThreshold, bootstrap: 1000, method: MethodMannWhitney}}
+	assert.NotZero(t, b.compare(control, experiment).PValue)
+
+	b.method = MethodBCa
+	assert.Equal(t, 1000, b.compare(control, experiment).Samples)
+}
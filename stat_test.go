@@ -166,3 +166,64 @@ func TestMedianDoesNotMutateInput(t *testing.T) {
 	assert.Equal(t, 2.0, median(data))
 	assert.Equal(t, []float64{3, 1, 2}, data)
 }
+
+func TestBCaBootstrapVerdict(t *testing.T) {
+	t.Parallel()
+
+	// Tight interval around zero is proven equivalent within the threshold
+	control := []float64{99.9, 100.0, 100.0, 100.1, 100.1, 99.8, 100.2, 100.0}
+	experiment := []float64{100.0, 100.1, 99.9, 100.0, 100.2, 99.9, 100.1, 100.0}
+	assert.Equal(t, VerdictEquivalent, bca(control, experiment, 0.95, 1000, defaultThreshold).Verdict)
+
+	// Clearly separated samples are reported as changed
+	faster := []float64{90.0, 90.1, 89.9, 90.0, 90.1, 89.8, 90.2, 90.0}
+	assert.Equal(t, VerdictChanged, bca(control, faster, 0.95, 1000, defaultThreshold).Verdict)
+
+	// Wide interval spanning the threshold band is inconclusive
+	noisy := []float64{70, 130, 80, 120, 95, 105, 60, 140}
+	assert.Equal(t, VerdictInconclusive, bca(control, noisy, 0.95, 1000, defaultThreshold).Verdict)
+}
+
+func TestIsEquivalent(t *testing.T) {
+	t.Parallel()
+
+	assert.True(t, isEquivalent(-0.01, 0.01, 5))
+	assert.False(t, isEquivalent(-0.01, 0.06, 5))
+	assert.False(t, isEquivalent(-0.01, 0.01, 0))
+	assert.False(t, isEquivalent(math.NaN(), 0.01, 5))
+	assert.Equal(t, VerdictEquivalent, classify(false, true, 0, 0, 5))
+	assert.Equal(t, VerdictInconclusive, classify(false, true, 0.01, 0.01, 5))
+	assert.Equal(t, VerdictChanged, classify(true, false, 0.1, 0.2, 5))
+	assert.Equal(t, "equivalent", VerdictEquivalent.String())
+	assert.Equal(t, "inconclusive", VerdictInconclusive.String())
+	assert.Equal(t, "changed", VerdictChanged.String())
+}
+
+func TestBonferroni(t *testing.T) {
+	t.Parallel()
+
+	assert.InDelta(t, 0.95, bonferroni(0.95, 0), 1e-12)
+	assert.InDelta(t, 0.95, bonferroni(0.95, 1), 1e-12)
+	assert.InDelta(t, 0.99, bonferroni(0.95, 5), 1e-12)
+	assert.InDelta(t, 1-0.001/150, bonferroni(0.999, 150), 1e-12)
+}
+
+func TestVerdictConstantSamples(t *testing.T) {
+	t.Parallel()
+
+	same := []float64{5, 5, 5, 5, 5, 5}
+	other := []float64{6, 6, 6, 6, 6, 6}
+
+	// Identical constant samples are the clearest case of equivalence
+	for _, report := range []Report{
+		bca(same, same, 0.99, 1000, defaultThreshold),
+		mannWhitney(same, same, 0.99, defaultThreshold),
+		bcaPaired(same, same, 0.99, 1000, defaultThreshold, 0),
+	} {
+		assert.True(t, report.Degenerate)
+		assert.Equal(t, VerdictEquivalent, report.Verdict)
+	}
+
+	// Constant but different samples have no interval to judge the change by
+	assert.Equal(t, VerdictInconclusive, bca(same, other, 0.99, 1000, defaultThreshold).Verdict)
+}
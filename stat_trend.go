@@ -0,0 +1,71 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root
+
+package bench
+
+import (
+	"math"
+	"sort"
+
+	"gonum.org/v1/gonum/stat/distuv"
+)
+
+// mannKendall performs a Mann-Kendall trend test over the sample index and
+// returns the two-sided p-value for the presence of a monotonic trend.
+func mannKendall(data []float64) float64 {
+	n := len(data)
+	if n < 3 {
+		return 1
+	}
+
+	var s float64
+	for i := 0; i < n-1; i++ {
+		for j := i + 1; j < n; j++ {
+			switch {
+			case data[j] > data[i]:
+				s++
+			case data[j] < data[i]:
+				s--
+			}
+		}
+	}
+
+	// Variance of S, corrected for groups of tied values
+	nf := float64(n)
+	variance := nf * (nf - 1) * (2*nf + 5)
+	sorted := append([]float64(nil), data...)
+	sort.Float64s(sorted)
+	for i := 0; i < n; {
+		j := i + 1
+		for j < n && sorted[j] == sorted[i] {
+			j++
+		}
+
+		t := float64(j - i)
+		variance -= t * (t - 1) * (2*t + 5)
+		i = j
+	}
+
+	variance /= 18
+	if variance <= 0 {
+		return 1
+	}
+
+	// Continuity correction towards zero
+	switch {
+	case s > 0:
+		s--
+	case s < 0:
+		s++
+	}
+
+	z := math.Abs(s) / math.Sqrt(variance)
+	return math.Min(1, 2*(1-distuv.UnitNormal.CDF(z)))
+}
+
+// hasTrend reports whether the samples drift over time at the given confidence,
+// which violates the assumption that samples are independent and identically
+// distributed.
+func hasTrend(data []float64, confidence float64) bool {
+	return mannKendall(data) < 1-normalizeConfidence(confidence)
+}
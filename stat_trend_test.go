@@ -0,0 +1,43 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root
+
+package bench
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMannKendall(t *testing.T) {
+	t.Parallel()
+
+	increasing := []float64{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}
+	assert.InDelta(t, 8.3e-5, mannKendall(increasing), 1e-5)
+
+	decreasing := []float64{10, 9, 8, 7, 6, 5, 4, 3, 2, 1}
+	assert.InDelta(t, mannKendall(increasing), mannKendall(decreasing), 1e-12)
+
+	assert.Equal(t, 1.0, mannKendall([]float64{5, 5, 5, 5}))
+	assert.Equal(t, 1.0, mannKendall([]float64{1, 2}))
+	assert.Greater(t, mannKendall([]float64{10, 11, 10, 11, 10, 11, 10, 11}), 0.5)
+}
+
+func TestHasTrend(t *testing.T) {
+	t.Parallel()
+
+	drift := make([]float64, 50)
+	stable := make([]float64, 50)
+	for i := range drift {
+		drift[i] = 100 + float64(i) + float64(i%3)
+		stable[i] = 100 + float64(i%5)
+	}
+
+	assert.True(t, hasTrend(drift, 0.999))
+	assert.False(t, hasTrend(stable, 0.999))
+	assert.Equal(t, "1.0 ms ~", formatTimeWithWarnings(1e6, Result{Unstable: true}))
+
+	b := &B{config: config{confidence: 99.9, threshold: defaultThreshold, bootstrap: 100}}
+	assert.True(t, b.compare(stable, drift).Unstable)
+	assert.False(t, b.compare(stable, stable).Unstable)
+}
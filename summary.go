@@ -0,0 +1,139 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root
+
+package bench
+
+import (
+	"fmt"
+	"math"
+	"math/rand/v2"
+)
+
+// Summary aggregates the comparisons of a benchmark suite into the geometric
+// mean of the per-benchmark ratios, with a bootstrap interval over benchmarks.
+type Summary struct {
+	Ratio        float64    // Ratio is the geometric mean of variant / control ratios
+	RatioCI      [2]float64 // RatioCI is the confidence interval for Ratio
+	Confidence   float64    // Confidence is the confidence level (e.g., 0.95 for 95%)
+	Verdict      Verdict    // Verdict is the three-way classification of RatioCI
+	Benchmarks   int        // Benchmarks is the number of comparisons summarized
+	Improved     int        // Improved is the number of significantly faster benchmarks
+	Regressed    int        // Regressed is the number of significantly slower benchmarks
+	Similar      int        // Similar is the number of benchmarks proven equivalent
+	Inconclusive int        // Inconclusive is the number of benchmarks neither changed nor equivalent
+}
+
+// Summarize returns the suite-wide summaries of the comparisons made so far,
+// against the previous run and against the reference implementations.
+func (r *B) Summarize() (vsPrev, vsRef Summary) {
+	confidence := r.confidence / 100.0
+	vsPrev = summarize(r.prevReports, confidence, r.bootstrap, r.threshold, r.seed)
+	vsRef = summarize(r.refReports, confidence, r.bootstrap, r.threshold, r.seed)
+	return
+}
+
+// summarize computes the geometric mean of the report ratios along with a BCa
+// bootstrap interval obtained by resampling benchmarks.
+func summarize(reports []Report, confidence float64, bootstrapSamples int, minChangePercent float64, seed uint64) Summary {
+	confidence = normalizeConfidence(confidence)
+	summary := Summary{Confidence: confidence}
+
+	deltas := make([]float64, 0, len(reports))
+	for _, report := range reports {
+		if report.Ratio <= 0 || !isFinite(report.Delta) {
+			continue
+		}
+
+		deltas = append(deltas, report.Delta)
+		switch {
+		case report.Significant && report.Delta < 0:
+			summary.Improved++
+		case report.Significant:
+			summary.Regressed++
+		case report.Verdict == VerdictEquivalent:
+			summary.Similar++
+		default:
+			summary.Inconclusive++
+		}
+	}
+
+	summary.Benchmarks = len(deltas)
+	if len(deltas) == 0 || bootstrapSamples <= 0 {
+		return summary
+	}
+
+	shift := mean(deltas)
+	seed1, seed2 := bootstrapSeed(len(deltas), len(deltas), bootstrapSamples, seed)
+	bootstrapStats := bootstrap(bootstrapSamples, seed1, seed2, meanShift(deltas))
+
+	biasCorrection := computeBiasCorrection(shift, bootstrapStats)
+	acceleration := jackknifeAcceleration(deltas, mean)
+	degenerate := degenerateBootstrap(bootstrapStats)
+	lowerCI, upperCI := computeBCaCI(bootstrapStats, biasCorrection, acceleration, 1-confidence)
+
+	significant := !degenerate && isSignificant(lowerCI, upperCI, shift, minChangePercent)
+	summary.Ratio = math.Exp(shift)
+	summary.RatioCI = [2]float64{math.Exp(lowerCI), math.Exp(upperCI)}
+	summary.Verdict = classify(significant, degenerate, lowerCI, upperCI, minChangePercent)
+	return summary
+}
+
+// meanShift returns a resampler of the mean log ratio across benchmarks.
+func meanShift(deltas []float64) func() resampler {
+	return func() resampler {
+		buffer := make([]float64, len(deltas))
+		return func(rng *rand.Rand) (float64, bool) {
+			resampleInto(buffer, deltas, rng)
+			return mean(buffer), true
+		}
+	}
+}
+
+// printSummary prints the suite-wide summary rows when at least two benchmarks
+// were compared.
+func (r *B) printSummary() {
+	vsPrev, vsRef := r.Summarize()
+	if vsPrev.Benchmarks < 2 && vsRef.Benchmarks < 2 {
+		return
+	}
+
+	if r.format == FormatJSON {
+		r.printJSON(summaryRecord{Type: "summary", VsPrev: newSummaryFields(vsPrev), VsRef: newSummaryFields(vsRef)})
+		return
+	}
+
+	r.printRow("geomean", "", "", "", "", formatSummary(vsPrev), formatSummary(vsRef))
+	r.printRow("", "", "", "", "", formatCounts(vsPrev), formatCounts(vsRef))
+}
+
+// formatSummary formats the geometric mean change with its confidence interval.
+func formatSummary(s Summary) string {
+	if s.Benchmarks < 2 || s.Ratio <= 0 {
+		return ""
+	}
+
+	interval := fmt.Sprintf("[%s, %s]", formatChange(ratioToChange(s.RatioCI[1])), formatChange(ratioToChange(s.RatioCI[0])))
+	switch s.Verdict {
+	case VerdictEquivalent:
+		return "🟰 " + interval
+	case VerdictInconclusive:
+		return "❔ " + interval
+	case VerdictChanged:
+		if s.Ratio < 1 {
+			return fmt.Sprintf("✅ %s %s", formatChange(ratioToChange(s.Ratio)), interval)
+		}
+		return fmt.Sprintf("❌ %s %s", formatChange(ratioToChange(s.Ratio)), interval)
+	default:
+		return interval
+	}
+}
+
+// formatCounts formats the number of improved, regressed, similar and
+// inconclusive benchmarks.
+func formatCounts(s Summary) string {
+	if s.Benchmarks < 2 {
+		return ""
+	}
+
+	return fmt.Sprintf("%d✅ %d❌ %d🟰 %d❔", s.Improved, s.Regressed, s.Similar, s.Inconclusive)
+}
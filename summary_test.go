@@ -0,0 +1,75 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root
+
+package bench
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSummarize(t *testing.T) {
+	t.Parallel()
+
+	reports := []Report{
+		{Delta: math.Log(0.95), Ratio: 0.95, Significant: true},
+		{Delta: math.Log(0.96), Ratio: 0.96, Significant: true},
+		{Delta: math.Log(0.97), Ratio: 0.97},
+		{Delta: math.Log(0.94), Ratio: 0.94, Significant: true},
+		{Delta: math.Log(1.10), Ratio: 1.10, Significant: true},
+		{}, // invalid comparisons are skipped
+	}
+
+	s := summarize(reports, 0.95, 2000, 1, 0)
+	assert.Equal(t, 5, s.Benchmarks)
+	assert.Equal(t, 3, s.Improved)
+	assert.Equal(t, 1, s.Regressed)
+	assert.Equal(t, 0, s.Similar)
+	assert.Equal(t, 1, s.Inconclusive)
+	assert.InDelta(t, math.Pow(0.95*0.96*0.97*0.94*1.10, 0.2), s.Ratio, 1e-12)
+	assert.True(t, s.RatioCI[0] <= s.Ratio && s.Ratio <= s.RatioCI[1])
+	assert.Equal(t, 0.95, s.Confidence)
+}
+
+func TestSummarizeVerdict(t *testing.T) {
+	t.Parallel()
+
+	faster := make([]Report, 10)
+	for i := range faster {
+		ratio := 0.80 + 0.01*float64(i%3)
+		faster[i] = Report{Delta: math.Log(ratio), Ratio: ratio, Significant: true}
+	}
+
+	s := summarize(faster, 0.95, 2000, defaultThreshold, 0)
+	assert.Equal(t, VerdictChanged, s.Verdict)
+	assert.Contains(t, formatSummary(s), "✅")
+	assert.Equal(t, "10✅ 0❌ 0🟰 0❔", formatCounts(s))
+
+	empty := summarize(nil, 0.95, 2000, defaultThreshold, 0)
+	assert.Equal(t, 0, empty.Benchmarks)
+	assert.Equal(t, "", formatSummary(empty))
+	assert.Equal(t, "", formatCounts(empty))
+}
+
+func TestRunCollectsReports(t *testing.T) {
+	file := "test_summary.json"
+	defer removeResults(file)
+
+	b := &B{config: config{filename: file, codec: jsonCodec{}}}
+	b.saveResult(Result{Name: "a", Samples: []float64{100, 101, 99, 100, 102}})
+	b.saveResult(Result{Name: "b", Samples: []float64{100, 101, 99, 100, 102}})
+
+	var runner *B
+	Run(func(b *B) {
+		runner = b
+		b.Run("a", func(i int) {}, func(i int) {})
+		b.Run("b", func(i int) {}, func(i int) {})
+	}, WithFile(file), WithSamples(5), WithDryRun(), WithBootstrap(1000))
+
+	vsPrev, vsRef := runner.Summarize()
+	assert.Equal(t, 2, vsPrev.Benchmarks)
+	assert.Equal(t, 2, vsRef.Benchmarks)
+	assert.Equal(t, 2, vsPrev.Improved+vsPrev.Regressed+vsPrev.Similar+vsPrev.Inconclusive)
+}